@@ -0,0 +1,143 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewIssueCmd issues a client certificate (and its private key) tied to an
+// identity ID for use with the settings CertificateAuthenticator, mirroring
+// the cfssl-based "sign a client cert for an agent" flow: it generates a
+// fresh RSA key, signs a certificate for it with a locally-held CA key/cert
+// pair (no CSR is read) and prints the Subject the
+// operator stores as that identity's CredentialsTypeCertificate credential
+// (see identity.CredentialsCertificateConfig).
+func NewIssueCmd() *cobra.Command {
+	var (
+		identityID string
+		caCertPath string
+		caKeyPath  string
+		out        string
+		outKey     string
+		validFor   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Issue a client certificate for privileged settings re-authentication",
+		Long: `Issue issues an mTLS client certificate and private key whose Subject
+encodes the given identity ID, signed by the provided CA key pair. The
+resulting Subject should be stored as that identity's
+CredentialsTypeCertificate credential so that requests presenting this
+certificate are treated as freshly privileged without an interactive login.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if identityID == "" {
+				return errors.New("--identity-id must be set")
+			}
+
+			caCertPEM, err := ioutil.ReadFile(caCertPath)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			caKeyPEM, err := ioutil.ReadFile(caKeyPath)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			caCertBlock, _ := pem.Decode(caCertPEM)
+			if caCertBlock == nil {
+				return errors.New("unable to decode CA certificate PEM")
+			}
+			caCert, err := x509.ParseCertificate(caCertBlock.Bytes)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			caKeyBlock, _ := pem.Decode(caKeyPEM)
+			if caKeyBlock == nil {
+				return errors.New("unable to decode CA key PEM")
+			}
+			caKey, err := x509.ParsePKCS1PrivateKey(caKeyBlock.Bytes)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			template := &x509.Certificate{
+				SerialNumber: serial,
+				Subject: pkix.Name{
+					CommonName: identityID,
+				},
+				NotBefore:   time.Now(),
+				NotAfter:    time.Now().Add(validFor),
+				KeyUsage:    x509.KeyUsageDigitalSignature,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}
+
+			der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			certOut := out
+			if certOut == "" {
+				f, err := ioutil.TempFile("", "kratos-agent-cert-*.pem")
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer f.Close()
+				certOut = f.Name()
+			}
+			keyOut := outKey
+			if keyOut == "" {
+				f, err := ioutil.TempFile("", "kratos-agent-key-*.pem")
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer f.Close()
+				keyOut = f.Name()
+			}
+
+			if err := ioutil.WriteFile(certOut, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+				return errors.WithStack(err)
+			}
+			if err := ioutil.WriteFile(keyOut, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+				return errors.WithStack(err)
+			}
+
+			cmd.Println("Subject:", template.Subject.String())
+			cmd.Println("Certificate written to:", certOut)
+			cmd.Println("Private key written to:", keyOut)
+			cmd.Println("Store this Subject as identity", identityID, "'s CredentialsTypeCertificate credential")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&identityID, "identity-id", "", "The identity ID this certificate authenticates as")
+	cmd.Flags().StringVar(&caCertPath, "ca-cert", "", "Path to the PEM-encoded CA certificate")
+	cmd.Flags().StringVar(&caKeyPath, "ca-key", "", "Path to the PEM-encoded CA private key")
+	cmd.Flags().StringVar(&out, "out", "", "Where to write the issued certificate (defaults to a temp file)")
+	cmd.Flags().StringVar(&outKey, "out-key", "", "Where to write the issued certificate's private key (defaults to a temp file)")
+	cmd.Flags().DurationVar(&validFor, "valid-for", 24*365*time.Hour, "How long the issued certificate should remain valid")
+
+	return cmd
+}
@@ -0,0 +1,18 @@
+package cert
+
+import "github.com/spf13/cobra"
+
+// NewCertCmd groups the certificate-related subcommands (currently just
+// "issue") under a single "cert" entry point. This tree has no root main
+// command of its own to mount it under yet - callers wiring up a kratos CLI
+// binary should add NewCertCmd() as a subcommand of their own root cobra.Command.
+func NewCertCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "Manage client certificates for privileged settings re-authentication",
+	}
+
+	cmd.AddCommand(NewIssueCmd())
+
+	return cmd
+}
@@ -0,0 +1,17 @@
+package configuration
+
+// Configuration exposes configuration values that are derived rather than
+// read directly off viper (e.g. decoded secrets), so call sites don't have
+// to re-parse them.
+type Configuration interface {
+	// SecretsSession returns the configured signing/encryption secrets
+	// (ViperKeySecretsDefault), newest first.
+	SecretsSession() [][]byte
+}
+
+// Provider is embedded by any dependencies interface that needs access to
+// Configuration, following the same pattern as identity.PoolProvider and
+// session.ManagerProvider.
+type Provider interface {
+	Configuration() Configuration
+}
@@ -0,0 +1,9 @@
+package configuration
+
+// ViperKeySelfServiceSettingsCertificateAuthenticatorCABundle points to a
+// PEM-encoded CA bundle used to verify client certificates presented on the
+// public API TLS handshake for privileged settings requests. Which identity
+// a given certificate authenticates as is not configured here - it's read
+// off the identity's own CredentialsTypeCertificate credential (see
+// identity.CredentialsCertificateConfig).
+const ViperKeySelfServiceSettingsCertificateAuthenticatorCABundle = "selfservice.settings.certificate_authenticator.ca_bundle"
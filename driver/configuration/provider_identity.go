@@ -0,0 +1,11 @@
+package configuration
+
+// DefaultIdentityTraitsSchemaID is the identity.Identity.TraitsSchemaID every
+// identity is assigned unless it was created against an explicitly named
+// alternative schema.
+const DefaultIdentityTraitsSchemaID = "default"
+
+// ViperKeyDefaultIdentityTraitsSchemaURL points to the JSON schema document
+// (a "file://" or "https://" URL) that DefaultIdentityTraitsSchemaID
+// resolves to.
+const ViperKeyDefaultIdentityTraitsSchemaURL = "identity.traits.default_schema_url"
@@ -0,0 +1,7 @@
+package configuration
+
+// ViperKeySelfServiceOIDCProviders points to the list of configured OIDC
+// providers (selfservice.methods.oidc.config.providers) that both the
+// login/registration oidc strategy and the settings link/unlink strategy
+// read their provider registry from.
+const ViperKeySelfServiceOIDCProviders = "selfservice.methods.oidc.config.providers"
@@ -0,0 +1,7 @@
+package configuration
+
+// ViperKeySecretsDefault points to the list of signing/encryption secrets,
+// newest first, that Configuration.SecretsSession decodes - used to sign the
+// settings flash-message cookie and anything else that needs a symmetric
+// secret rather than a per-identity credential.
+const ViperKeySecretsDefault = "secrets.default"
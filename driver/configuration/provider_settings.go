@@ -0,0 +1,15 @@
+package configuration
+
+const (
+	// ViperKeySelfServicePrivilegedAuthenticationAfter is how recently an
+	// identity must have completed an interactive login for
+	// settings.RequirePrivilegedSession to treat its session as sudo-capable
+	// without a fresh re-authentication (or an equivalent, such as a trusted
+	// client certificate).
+	ViperKeySelfServicePrivilegedAuthenticationAfter = "selfservice.flows.settings.privileged_session_max_age"
+
+	// ViperKeySelfServiceSettingsAfterConfig points to the per-strategy
+	// after-hooks config (selfservice.flows.settings.after.<method>.hooks)
+	// that Handler.runAfterHooks reads once a settings update succeeds.
+	ViperKeySelfServiceSettingsAfterConfig = "selfservice.flows.settings.after"
+)
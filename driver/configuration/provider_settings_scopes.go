@@ -0,0 +1,6 @@
+package configuration
+
+// ViperKeySelfServiceSettingsScopes points to the selfservice.settings.scopes
+// config map declaring, per scope, whether it requires sudo mode, a
+// verification challenge, and/or is rate-limited.
+const ViperKeySelfServiceSettingsScopes = "selfservice.settings.scopes"
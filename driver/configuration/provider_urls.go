@@ -0,0 +1,18 @@
+package configuration
+
+const (
+	// ViperKeyURLsSelfPublic is the externally reachable base URL of the
+	// public API, used to build absolute redirect URLs (e.g. the oidc
+	// strategy's OAuth2 callback) that must survive a round trip through a
+	// third-party provider.
+	ViperKeyURLsSelfPublic = "urls.self.public"
+
+	// ViperKeyURLsLogin points to the login UI that RedirectToLogin sends the
+	// browser to when a sudo-gated action is attempted without a privileged
+	// session.
+	ViperKeyURLsLogin = "urls.login_ui"
+
+	// ViperKeyURLsSettingsUI points to the settings UI that Handler.initFlow
+	// redirects the browser to once a settings Flow has been created.
+	ViperKeyURLsSettingsUI = "urls.settings_ui"
+)
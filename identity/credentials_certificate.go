@@ -0,0 +1,31 @@
+package identity
+
+import "encoding/json"
+
+// CredentialsTypeCertificate is the CredentialsType for mutual-TLS client
+// certificate authentication. Unlike password or oidc credentials, it is
+// never used to start a login flow - it only ever proves that a request was
+// presented with a trusted client certificate so that it can be treated as
+// freshly privileged (see selfservice/flow/settings.CertificateAuthenticator).
+const CredentialsTypeCertificate CredentialsType = "certificate"
+
+// CredentialsCertificateConfig is the config stored alongside a
+// CredentialsTypeCertificate credential. It is the identity's own allow-list
+// entry: the CertificateAuthenticator matches the client certificate against
+// it once the certificate has been verified against the configured CA bundle.
+//
+// swagger:model credentialsCertificateConfig
+type CredentialsCertificateConfig struct {
+	// Subject is the expected X509v3 Subject (e.g. "CN=agent-1,O=Ory Corp")
+	// of the presented client certificate.
+	Subject string `json:"subject"`
+
+	// SAN is an optional Subject Alternative Name (URI, DNS, or email) that,
+	// if set, must also be present on the certificate.
+	SAN string `json:"san,omitempty"`
+}
+
+func (c CredentialsCertificateConfig) Marshal() []byte {
+	out, _ := json.Marshal(c)
+	return out
+}
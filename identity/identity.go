@@ -0,0 +1,68 @@
+// Package identity owns the Identity aggregate - an identity's credentials
+// and its JSON-schema-validated Traits - together with the Pool that
+// persists it and the SchemaProvider that resolves the schema a given
+// identity's traits are validated against.
+package identity
+
+import (
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+)
+
+// CredentialsType identifies the authentication method a Credentials entry
+// belongs to, e.g. "password", "oidc", or CredentialsTypeCertificate.
+type CredentialsType string
+
+// CredentialsTypeOIDC is the CredentialsType for linked social sign-in
+// providers (see selfservice/flow/settings.StrategyOIDC).
+const CredentialsTypeOIDC CredentialsType = "oidc"
+
+// Credentials is one authentication method configured for an Identity.
+// Identifiers is how that method looks this identity up (a password
+// identity's email, an oidc identity's "provider:subject" pairs, ...);
+// Config is whatever method-specific secret material it needs (a password
+// hash, a CredentialsCertificateConfig, ...).
+type Credentials struct {
+	Type        CredentialsType `json:"type"`
+	Identifiers []string        `json:"identifiers,omitempty"`
+	Config      json.RawMessage `json:"config,omitempty"`
+}
+
+// Traits is an identity's JSON-schema-validated trait document (e.g.
+// {"email": "...", ...}). It is kept as raw JSON, rather than unmarshalled
+// into a Go struct, because its shape is entirely defined by whatever schema
+// TraitsSchemaID points to.
+type Traits json.RawMessage
+
+// MarshalJSON writes t as the raw JSON document it holds.
+func (t Traits) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	return t, nil
+}
+
+// UnmarshalJSON stores data as-is.
+func (t *Traits) UnmarshalJSON(data []byte) error {
+	*t = append((*t)[0:0], data...)
+	return nil
+}
+
+// Identity is a single end-user account.
+//
+// swagger:model identity
+type Identity struct {
+	ID uuid.UUID `json:"id"`
+
+	// Credentials holds every authentication method currently configured
+	// for this identity, keyed by CredentialsType.
+	Credentials map[CredentialsType]Credentials `json:"credentials,omitempty"`
+
+	// Traits is validated against the JSON schema TraitsSchemaID resolves to
+	// (see SchemaProvider).
+	Traits Traits `json:"traits"`
+
+	// TraitsSchemaID names the JSON schema Traits must validate against.
+	TraitsSchemaID string `json:"schema_id"`
+}
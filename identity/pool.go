@@ -0,0 +1,19 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+// Pool persists and retrieves identities.
+type Pool interface {
+	CreateIdentity(ctx context.Context, i *Identity) error
+	GetIdentity(ctx context.Context, id uuid.UUID) (*Identity, error)
+	UpdateIdentity(ctx context.Context, i *Identity) error
+	FindByCredentialsIdentifier(ctx context.Context, ct CredentialsType, identifier string) (*Identity, error)
+}
+
+type PoolProvider interface {
+	IdentityPool() Pool
+}
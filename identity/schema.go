@@ -0,0 +1,14 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// SchemaProvider resolves the raw JSON schema document an identity's
+// TraitsSchemaID points to. Settings scope enforcement uses it to read each
+// trait's `ory.sh/kratos.scopes` annotation without having to recompile the
+// schema itself.
+type SchemaProvider interface {
+	IdentityTraitsSchema(ctx context.Context, schemaID string) (json.RawMessage, error)
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/flow/settings"
+)
+
+// flowPersister is an in-memory settings.FlowPersister, standing in for a
+// real SQL-backed store.
+type flowPersister struct {
+	mu    sync.Mutex
+	flows map[uuid.UUID]*settings.Flow
+}
+
+func newFlowPersister() *flowPersister {
+	return &flowPersister{flows: map[uuid.UUID]*settings.Flow{}}
+}
+
+func (p *flowPersister) CreateSettingsFlow(_ context.Context, _ *http.Request, f *settings.Flow) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flows[f.ID] = f
+	return nil
+}
+
+func (p *flowPersister) GetSettingsFlow(_ context.Context, id uuid.UUID) (*settings.Flow, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, ok := p.flows[id]
+	if !ok {
+		return nil, errors.Errorf("settings flow %s not found", id)
+	}
+	return f, nil
+}
+
+func (p *flowPersister) UpdateSettingsFlow(_ context.Context, f *settings.Flow) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.flows[f.ID]; !ok {
+		return errors.Errorf("settings flow %s not found", f.ID)
+	}
+	p.flows[f.ID] = f
+	return nil
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// identityPool is an in-memory identity.Pool: a map guarded by a mutex,
+// standing in for a real SQL-backed pool.
+type identityPool struct {
+	mu         sync.Mutex
+	identities map[uuid.UUID]*identity.Identity
+}
+
+func newIdentityPool() *identityPool {
+	return &identityPool{identities: map[uuid.UUID]*identity.Identity{}}
+}
+
+func (p *identityPool) CreateIdentity(_ context.Context, i *identity.Identity) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if i.ID == uuid.Nil {
+		i.ID = x.NewUUID()
+	}
+
+	cp := *i
+	p.identities[i.ID] = &cp
+	return nil
+}
+
+func (p *identityPool) GetIdentity(_ context.Context, id uuid.UUID) (*identity.Identity, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i, ok := p.identities[id]
+	if !ok {
+		return nil, errors.Errorf("identity %s not found", id)
+	}
+
+	cp := *i
+	return &cp, nil
+}
+
+func (p *identityPool) UpdateIdentity(_ context.Context, i *identity.Identity) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.identities[i.ID]; !ok {
+		return errors.Errorf("identity %s not found", i.ID)
+	}
+
+	cp := *i
+	p.identities[i.ID] = &cp
+	return nil
+}
+
+func (p *identityPool) FindByCredentialsIdentifier(_ context.Context, ct identity.CredentialsType, identifier string) (*identity.Identity, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, i := range p.identities {
+		creds, ok := i.Credentials[ct]
+		if !ok {
+			continue
+		}
+		for _, id := range creds.Identifiers {
+			if id == identifier {
+				cp := *i
+				return &cp, nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("no identity found with a %q credential identifier %q", ct, identifier)
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+// SessionCookieName is the cookie a test fixture sets (see
+// internal/testhelpers' "/sessions/set/:index" route) to authenticate as a
+// given identity. It is exported so testhelpers can set it directly without
+// this package needing to expose an HTTP handler of its own.
+const SessionCookieName = "kratos_test_session_identity"
+
+// sessionManager is an in-memory session.Manager: the session is never
+// actually stored anywhere, it is recomputed from the identity named by
+// SessionCookieName on every call, with AuthenticatedAt always "now" - good
+// enough to stand in for a real cookie-backed session store, since no test
+// in this tree asserts on session ID stability across requests.
+type sessionManager struct {
+	identities *identityPool
+}
+
+func newSessionManager(pool *identityPool) *sessionManager {
+	return &sessionManager{identities: pool}
+}
+
+func (m *sessionManager) FetchFromRequest(r *http.Request) (*session.Session, error) {
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	id, err := uuid.FromString(cookie.Value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	i, err := m.identities.GetIdentity(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session.Session{
+		ID:              x.NewUUID(),
+		Identity:        i,
+		AuthenticatedAt: time.Now(),
+		ExpiresAt:       time.Now().Add(time.Hour),
+	}, nil
+}
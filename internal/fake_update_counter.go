@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/selfservice/flow/settings"
+)
+
+// updateCounter is an in-memory settings.UpdateCounter, keyed by identity
+// and scope - standing in for a real per-day rate-limit store.
+type updateCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newUpdateCounter() *updateCounter {
+	return &updateCounter{counts: map[string]int{}}
+}
+
+func key(identityID uuid.UUID, scope settings.Scope) string {
+	return identityID.String() + "|" + string(scope)
+}
+
+func (c *updateCounter) CountUpdatesToday(_ context.Context, identityID uuid.UUID, scope settings.Scope) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts[key(identityID, scope)], nil
+}
+
+func (c *updateCounter) RecordUpdate(_ context.Context, identityID uuid.UUID, scope settings.Scope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[key(identityID, scope)]++
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package common is the minimal hand-written slice of a generated OpenAPI
+// client this tree needs: just enough to fetch a settings flow (and, since
+// this tree has no standalone login self-service flow, the same settings
+// flow endpoint doubling as the "login request" lookup RedirectToLogin's
+// login UI uses to find its way back) over plain HTTP.
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/internal/httpclient/models"
+)
+
+// settingsRequestPath matches settings.PublicPath + "/requests", mounted on
+// both the public and admin routers by settings.Handler.
+const settingsRequestPath = "/self-service/browser/flows/requests/settings/requests"
+
+// Client is the "Common" service of the SDK, grouping the self-service
+// browser flow lookups that don't belong to any single strategy.
+type Client struct {
+	basePath   string
+	httpClient *http.Client
+}
+
+// New constructs a Client against basePath (a running public or admin API's
+// base URL).
+func New(basePath string) *Client {
+	return &Client{basePath: basePath, httpClient: http.DefaultClient}
+}
+
+func (c *Client) clientFor(override *http.Client) *http.Client {
+	if override != nil {
+		return override
+	}
+	return c.httpClient
+}
+
+// GetSelfServiceBrowserSettingsRequestOK is the 200 response envelope
+// GetSelfServiceBrowserSettingsRequest returns.
+type GetSelfServiceBrowserSettingsRequestOK struct {
+	Payload *models.SettingsFlow
+}
+
+// GetSelfServiceBrowserSettingsRequest fetches the settings flow named by
+// params.Request.
+func (c *Client) GetSelfServiceBrowserSettingsRequest(params *GetSelfServiceBrowserSettingsRequestParams) (*GetSelfServiceBrowserSettingsRequestOK, error) {
+	var payload models.SettingsFlow
+	if err := c.get(params.HTTPClient, settingsRequestPath, params.Request, &payload); err != nil {
+		return nil, err
+	}
+	return &GetSelfServiceBrowserSettingsRequestOK{Payload: &payload}, nil
+}
+
+// GetSelfServiceBrowserLoginRequestOK is the 200 response envelope
+// GetSelfServiceBrowserLoginRequest returns.
+type GetSelfServiceBrowserLoginRequestOK struct {
+	Payload *models.LoginRequest
+}
+
+// GetSelfServiceBrowserLoginRequest fetches the login challenge named by
+// params.Request - in this tree, the same settings flow RedirectToLogin
+// bounced away from, looked up by its own ID.
+func (c *Client) GetSelfServiceBrowserLoginRequest(params *GetSelfServiceBrowserLoginRequestParams) (*GetSelfServiceBrowserLoginRequestOK, error) {
+	var payload models.LoginRequest
+	if err := c.get(params.HTTPClient, settingsRequestPath, params.Request, &payload); err != nil {
+		return nil, err
+	}
+	return &GetSelfServiceBrowserLoginRequestOK{Payload: &payload}, nil
+}
+
+func (c *Client) get(override *http.Client, path, request string, out interface{}) error {
+	res, err := c.clientFor(override).Get(c.basePath + path + "?request=" + url.QueryEscape(request))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d from %s", res.StatusCode, path)
+	}
+
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	return errors.WithStack(dec.Decode(out))
+}
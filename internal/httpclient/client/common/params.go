@@ -0,0 +1,47 @@
+package common
+
+import "net/http"
+
+// GetSelfServiceBrowserSettingsRequestParams carries the "request" ID query
+// param and optional HTTP client a GetSelfServiceBrowserSettingsRequest call
+// needs, matching the builder-style parameter object a generated OpenAPI
+// client would produce.
+type GetSelfServiceBrowserSettingsRequestParams struct {
+	Request    string
+	HTTPClient *http.Client
+}
+
+func NewGetSelfServiceBrowserSettingsRequestParams() *GetSelfServiceBrowserSettingsRequestParams {
+	return &GetSelfServiceBrowserSettingsRequestParams{}
+}
+
+func (p *GetSelfServiceBrowserSettingsRequestParams) WithRequest(request string) *GetSelfServiceBrowserSettingsRequestParams {
+	p.Request = request
+	return p
+}
+
+func (p *GetSelfServiceBrowserSettingsRequestParams) WithHTTPClient(client *http.Client) *GetSelfServiceBrowserSettingsRequestParams {
+	p.HTTPClient = client
+	return p
+}
+
+// GetSelfServiceBrowserLoginRequestParams is the login-challenge equivalent
+// of GetSelfServiceBrowserSettingsRequestParams.
+type GetSelfServiceBrowserLoginRequestParams struct {
+	Request    string
+	HTTPClient *http.Client
+}
+
+func NewGetSelfServiceBrowserLoginRequestParams() *GetSelfServiceBrowserLoginRequestParams {
+	return &GetSelfServiceBrowserLoginRequestParams{}
+}
+
+func (p *GetSelfServiceBrowserLoginRequestParams) WithRequest(request string) *GetSelfServiceBrowserLoginRequestParams {
+	p.Request = request
+	return p
+}
+
+func (p *GetSelfServiceBrowserLoginRequestParams) WithHTTPClient(client *http.Client) *GetSelfServiceBrowserLoginRequestParams {
+	p.HTTPClient = client
+	return p
+}
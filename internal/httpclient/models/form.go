@@ -0,0 +1,29 @@
+package models
+
+// FormFieldError is a single validation error attached to a FormField,
+// decoded off the matching form.FieldError the server rendered.
+type FormFieldError struct {
+	Message *string `json:"message"`
+}
+
+// FormField is the SDK's decoding of a form.Field. Action/Method are only
+// set when the field overrides its owning Form's own Action/Method.
+type FormField struct {
+	Name     *string           `json:"name"`
+	Type     *string           `json:"type"`
+	Value    interface{}       `json:"value,omitempty"`
+	Required bool              `json:"required,omitempty"`
+	Errors   []*FormFieldError `json:"errors,omitempty"`
+	Action   *string           `json:"action,omitempty"`
+	Method   *string           `json:"method,omitempty"`
+}
+
+// FormFields is a rendered form's field list, in submission order.
+type FormFields []*FormField
+
+// Form is the SDK's decoding of a form.HTMLForm.
+type Form struct {
+	Action *string    `json:"action"`
+	Method *string    `json:"method"`
+	Fields FormFields `json:"fields"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// Identity is the SDK's decoding of the identity embedded in a settings flow
+// response. Traits is left as a decoded interface{} (rather than raw bytes)
+// because the SDK has no equivalent of identity.Traits - the generated
+// client only ever sees identities as JSON it re-decodes generically.
+type Identity struct {
+	ID             UUID        `json:"id"`
+	Traits         interface{} `json:"traits"`
+	TraitsSchemaID *string     `json:"schema_id"`
+}
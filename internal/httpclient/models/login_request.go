@@ -0,0 +1,11 @@
+package models
+
+// LoginRequest is the SDK's decoding of a login challenge lookup. This tree
+// has no standalone login self-service flow of its own - RequestURL is
+// served off the same settings Flow the "request" parameter names: the URL
+// that Flow was initiated from. settings.RedirectToLogin passes return_to
+// to the login UI only in the redirect's query string; it does not store
+// return_to on the Flow, so RequestURL does not carry it.
+type LoginRequest struct {
+	RequestURL *string `json:"request_url"`
+}
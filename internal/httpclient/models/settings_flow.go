@@ -0,0 +1,17 @@
+package models
+
+// SettingsFlowMethod is the SDK's decoding of a settings.FlowMethod.
+type SettingsFlowMethod struct {
+	Config *Form `json:"config"`
+}
+
+// SettingsFlow is the SDK's decoding of the settingsFlowPayload the settings
+// Handler's fetchFlow endpoint returns.
+type SettingsFlow struct {
+	ID                  UUID                           `json:"id"`
+	Identity            *Identity                      `json:"identity"`
+	RequestURL          *string                        `json:"request_url"`
+	Methods             map[string]*SettingsFlowMethod `json:"methods"`
+	UpdateSuccessful    *bool                          `json:"update_successful"`
+	PendingVerification bool                           `json:"pending_verification,omitempty"`
+}
@@ -0,0 +1,10 @@
+// Package models is the minimal slice of a generated OpenAPI client's model
+// package this tree needs: just enough of the settings-flow and form shapes
+// for internal/testhelpers and the settings strategy tests to talk to a real
+// HTTP server instead of calling Handler/Strategy methods directly.
+package models
+
+// UUID is the SDK's wire representation of an ID - a plain string rather
+// than the uuid.UUID type the server side uses, matching how a generated
+// OpenAPI client would decode one.
+type UUID string
@@ -0,0 +1,116 @@
+// Package internal wires together the in-memory RegistryDefault every test
+// in this tree is built against. It plays the role a real dependency-
+// injection registry (backed by a SQL identity pool, a real session cookie
+// store, ...) would in production, but keeps everything in memory so tests
+// don't need a database.
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/session"
+)
+
+// RegistryDefault satisfies every dependencies interface the settings flow
+// (Handler, StrategyTraits, StrategyOIDC, flash) needs, backed by in-memory
+// fakes wherever this trimmed tree has no real persistence layer of its own.
+type RegistryDefault struct {
+	identities *identityPool
+	flows      *flowPersister
+	sessions   *sessionManager
+	counter    *updateCounter
+
+	strategies settings.Strategies
+	handler    *settings.Handler
+}
+
+// NewRegistryDefault builds a fresh RegistryDefault for t. The first return
+// value is the Configuration a caller would normally read secrets/URLs off
+// directly - RegistryDefault implements it itself, same as it does every
+// other provider interface, so both return values are usually the same
+// value viewed through different lenses.
+func NewRegistryDefault(t *testing.T) (configuration.Configuration, *RegistryDefault) {
+	reg := &RegistryDefault{
+		identities: newIdentityPool(),
+		flows:      newFlowPersister(),
+		counter:    newUpdateCounter(),
+	}
+	reg.sessions = newSessionManager(reg.identities)
+	reg.strategies = settings.Strategies{
+		settings.NewStrategyTraits(reg),
+		settings.NewStrategyOIDC(reg),
+	}
+	reg.handler = settings.NewHandler(reg)
+
+	return reg, reg
+}
+
+func (r *RegistryDefault) Configuration() configuration.Configuration { return r }
+
+// SecretsSession reads selfservice's configured secrets directly off viper,
+// same as every ViperKey* elsewhere in this tree.
+func (r *RegistryDefault) SecretsSession() [][]byte {
+	var secrets []string
+	_ = viper.UnmarshalKey(configuration.ViperKeySecretsDefault, &secrets)
+
+	out := make([][]byte, len(secrets))
+	for i, s := range secrets {
+		out[i] = []byte(s)
+	}
+	return out
+}
+
+func (r *RegistryDefault) IdentityPool() identity.Pool { return r.identities }
+
+// IdentityTraitsSchema resolves the configured default schema URL as a
+// "file://" path relative to the test package's own working directory -
+// this tree has only ever configured one schema in tests, so schemaID
+// itself is not consulted.
+func (r *RegistryDefault) IdentityTraitsSchema(ctx context.Context, schemaID string) (json.RawMessage, error) {
+	path := strings.TrimPrefix(viper.GetString(configuration.ViperKeyDefaultIdentityTraitsSchemaURL), "file://")
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return raw, nil
+}
+
+func (r *RegistryDefault) SessionManager() session.Manager { return r.sessions }
+
+func (r *RegistryDefault) SettingsFlowPersister() settings.FlowPersister { return r.flows }
+
+func (r *RegistryDefault) SettingsStrategies() settings.Strategies { return r.strategies }
+
+func (r *RegistryDefault) SettingsHandler() *settings.Handler { return r.handler }
+
+func (r *RegistryDefault) SettingsUpdateCounter() settings.UpdateCounter { return r.counter }
+
+// VerificationManager is a no-op: no test in this tree asserts on what a
+// verification challenge actually sends, only that Settle queued one (see
+// Flow.PendingVerification).
+func (r *RegistryDefault) VerificationManager() verification.Manager { return noopVerificationManager{} }
+
+func (r *RegistryDefault) OIDCProviders() []settings.OIDCProviderConfig {
+	var providers []settings.OIDCProviderConfig
+	_ = viper.UnmarshalKey(configuration.ViperKeySelfServiceOIDCProviders, &providers)
+	return providers
+}
+
+type noopVerificationManager struct{}
+
+func (noopVerificationManager) SendChallenge(_ context.Context, _ *identity.Identity, _ string) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package testhelpers
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// NewSessionClient GETs fakeSessionURL (a test server's "set session N"
+// endpoint) with a fresh cookie jar and returns the resulting client, now
+// carrying whatever session cookie that endpoint set - the plain-HTTP
+// counterpart of NewSessionClientWithHTTPClient.
+func NewSessionClient(t *testing.T, fakeSessionURL string) *http.Client {
+	return NewSessionClientWithHTTPClient(t, fakeSessionURL, &http.Client{})
+}
+
+// NewSessionClientWithHTTPClient behaves like NewSessionClient but lets the
+// caller supply the underlying http.Client - e.g. one configured with a
+// client TLS certificate for tests against the certificate authenticator.
+func NewSessionClientWithHTTPClient(t *testing.T, fakeSessionURL string, base *http.Client) *http.Client {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		Jar:       jar,
+		Transport: base.Transport,
+	}
+
+	res, err := client.Get(fakeSessionURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.EqualValues(t, http.StatusOK, res.StatusCode)
+
+	return client
+}
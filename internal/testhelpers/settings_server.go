@@ -0,0 +1,198 @@
+package testhelpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/pointerx"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/httpclient/client/common"
+	"github.com/ory/kratos/internal/httpclient/models"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/x"
+)
+
+// NewSettingsAPIServer wires reg's settings strategies and handler onto a
+// fresh public and admin httprouter.Router, seeds identities into reg's
+// identity pool, and serves both over httptest - the HTTP-level counterpart
+// of calling Handler/Strategy methods directly, so a test exercises the same
+// route wiring and CSRF/session plumbing a real browser would hit. Use the
+// "/sessions/set/:index" route NewSessionClient(WithHTTPClient) GETs to
+// authenticate as identities[index].
+func NewSettingsAPIServer(t *testing.T, reg *internal.RegistryDefault, identities []identity.Identity) (public *httptest.Server, admin *httptest.Server) {
+	for i := range identities {
+		require.NoError(t, reg.IdentityPool().CreateIdentity(context.Background(), &identities[i]))
+	}
+
+	pr, ar := x.NewRouterPublic(), x.NewRouterAdmin()
+	reg.SettingsStrategies().RegisterPublicRoutes(pr)
+	reg.SettingsHandler().RegisterPublicRoutes(pr)
+	reg.SettingsHandler().RegisterAdminRoutes(ar)
+	pr.GET("/sessions/set/:index", newSetSessionHandler(identities))
+
+	publicTS := httptest.NewServer(pr)
+	adminTS := httptest.NewServer(ar)
+	t.Cleanup(publicTS.Close)
+	t.Cleanup(adminTS.Close)
+
+	return publicTS, adminTS
+}
+
+// newSetSessionHandler authenticates the caller as identities[index] by
+// setting internal.SessionCookieName - the same cookie fake_session_manager
+// recomputes a session.Session from on every request.
+func newSetSessionHandler(identities []identity.Identity) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		index, err := strconv.Atoi(ps.ByName("index"))
+		if err != nil || index < 0 || index >= len(identities) {
+			http.Error(w, "unknown session index", http.StatusBadRequest)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: internal.SessionCookieName, Value: identities[index].ID.String(), Path: "/"})
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// NewSettingsUITestServer stands in for the settings UI the public API
+// redirects to once a settings Flow is created, and points
+// ViperKeyURLsSettingsUI at it. Its only route, GET /settings, is where a
+// browser would actually render the form - tests only care that the
+// redirect landed there, not what it renders.
+func NewSettingsUITestServer(t *testing.T) *httptest.Server {
+	router := httprouter.New()
+	router.GET("/settings", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	viper.Set(configuration.ViperKeyURLsSettingsUI, ts.URL+"/settings")
+	return ts
+}
+
+// sdkClient groups the generated-client services a settings test needs,
+// mirroring how a real OpenAPI-generated client splits itself by tag.
+type sdkClient struct {
+	Common *common.Client
+}
+
+// NewSDKClient builds an sdkClient against ts.
+func NewSDKClient(ts *httptest.Server) *sdkClient {
+	return &sdkClient{Common: common.New(ts.URL)}
+}
+
+// GetSettingsMethodConfig starts a fresh settings Flow as client against ts
+// and returns the rendered form for method (e.g. settings.StrategyTraitsID).
+func GetSettingsMethodConfig(t *testing.T, client *http.Client, ts *httptest.Server, method string) *models.Form {
+	res, err := client.Get(ts.URL + settings.PublicPath)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	rid := res.Request.URL.Query().Get("request")
+	require.NotEmptyf(t, rid, "no ?request= on redirected URL %s", res.Request.URL)
+
+	pr, err := common.New(ts.URL).GetSelfServiceBrowserSettingsRequest(
+		common.NewGetSelfServiceBrowserSettingsRequestParams().WithHTTPClient(client).WithRequest(rid),
+	)
+	require.NoError(t, err)
+
+	m, ok := pr.Payload.Methods[method]
+	require.Truef(t, ok, "no method %q in settings flow %s", method, rid)
+
+	return m.Config
+}
+
+// SDKFormFieldsToURLValues flattens a rendered form's fields into the
+// url.Values its action expects back, the same shape a browser's own form
+// submission would produce.
+func SDKFormFieldsToURLValues(fields models.FormFields) url.Values {
+	values := url.Values{}
+	for _, field := range fields {
+		if field.Name == nil {
+			continue
+		}
+		values.Set(*field.Name, stringifyFormValue(field.Value))
+	}
+	return values
+}
+
+// stringifyFormValue renders a FormField's decoded value (a string,
+// json.Number, or bool) back into the plain string form.Field.Value would
+// have come from, so re-submitting an untouched field round-trips it
+// unchanged.
+func stringifyFormValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return ""
+	}
+}
+
+// SettingsSubmitForm POSTs values to form.Action as client and decodes the
+// response body as a settings Flow, returning both the raw body (for
+// gjson-based field assertions) and the typed SDK response envelope.
+func SettingsSubmitForm(t *testing.T, form *models.Form, client *http.Client, values url.Values) (string, *common.GetSelfServiceBrowserSettingsRequestOK) {
+	require.NotNil(t, form)
+
+	res, err := client.PostForm(pointerx.StringR(form.Action), values)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var payload models.SettingsFlow
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&payload), "%s", body)
+
+	return string(body), &common.GetSelfServiceBrowserSettingsRequestOK{Payload: &payload}
+}
+
+// SetSettingsStrategyAfterHooks points method's after-update redirect hook
+// (selfservice.flows.settings.after.<method>.hooks.0.config.default_redirect_url)
+// at redirectTo.
+func SetSettingsStrategyAfterHooks(t *testing.T, method string, redirectTo string) {
+	viper.Set(configuration.ViperKeySelfServiceSettingsAfterConfig+"."+method+".hooks.0.config.default_redirect_url", redirectTo)
+}
+
+// HookConfigRedirectTo builds the value a whole
+// selfservice.flows.settings.after.<method> entry would hold for a single
+// "redirect to redirectTo" hook, for tests that set the entry wholesale
+// rather than going through SetSettingsStrategyAfterHooks's single key.
+func HookConfigRedirectTo(t *testing.T, redirectTo string) interface{} {
+	return map[string]interface{}{
+		"hooks": []interface{}{
+			map[string]interface{}{
+				"config": map[string]interface{}{
+					"default_redirect_url": redirectTo,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package flash
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/x"
+)
+
+// PublicFlashesPath is exposed as the `GET /self-service/browser/flashes`
+// SDK endpoint. It returns whatever flash messages are pending for the
+// caller and clears the cookie on read, so a message is only ever delivered
+// once.
+const PublicFlashesPath = "/self-service/browser/flashes"
+
+type handlerDependencies interface {
+	dependencies
+}
+
+// Handler serves the flash-message read endpoint. It has no persistence of
+// its own - state lives entirely in the signed ory_kratos_flash cookie.
+type Handler struct {
+	d handlerDependencies
+}
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterPublicRoutes(public *httprouter.Router) {
+	public.GET(PublicFlashesPath, h.fetchFlashes)
+}
+
+func (h *Handler) fetchFlashes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	messages := Consume(w, r, h.d)
+	if messages == nil {
+		messages = []Message{}
+	}
+	x.WriteJSON(w, r, messages)
+}
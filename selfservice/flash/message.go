@@ -0,0 +1,22 @@
+// Package flash implements a signed, one-shot flash-message channel that
+// lets a self-service flow (and its after-hooks) leave a short note for the
+// page the browser is redirected to - e.g. "settings updated", "sudo
+// required" - without having to thread that state through the redirect URL
+// itself.
+package flash
+
+// Message is a single flash entry. ID is an i18n message ID, not rendered
+// text - the UI stays responsible for translation. Args carries any
+// placeholders the translated string needs (e.g. {"field": "email"}).
+//
+// swagger:model flashMessage
+type Message struct {
+	// Level is the message's severity, e.g. "success", "info", "error".
+	Level string `json:"level"`
+
+	// ID is an i18n message ID such as "settings_profile_updated".
+	ID string `json:"id"`
+
+	// Args are named placeholders for the translated message.
+	Args map[string]interface{} `json:"args,omitempty"`
+}
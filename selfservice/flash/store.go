@@ -0,0 +1,111 @@
+package flash
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/configuration"
+)
+
+// CookieName is the cookie used to carry pending flash messages across a
+// cross-origin redirect to return_to. It is cleared by the public API the
+// first time GET /self-service/browser/flashes is called.
+const CookieName = "ory_kratos_flash"
+
+// maxCookieBytes bounds the serialized, signed cookie value. If adding a new
+// message would exceed this, the oldest messages are evicted first so a
+// chatty after-hook chain can never grow the cookie unboundedly.
+const maxCookieBytes = 4096
+
+type dependencies interface {
+	configuration.Provider
+}
+
+func codec(d dependencies) *securecookie.SecureCookie {
+	secrets := d.Configuration().SecretsSession()
+	var hashKey, blockKey []byte
+	if len(secrets) > 0 {
+		hashKey = secrets[0]
+	}
+	// securecookie's default MaxLength (4096) makes Encode itself fail once
+	// the serialized value crosses it, which would short-circuit write's
+	// oldest-first eviction loop before it ever runs. Disabling the codec's
+	// own limit and enforcing maxCookieBytes ourselves is what lets that
+	// loop actually evict down to something that fits.
+	return securecookie.New(hashKey, blockKey).MaxLength(0)
+}
+
+func read(r *http.Request, d dependencies) []Message {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil
+	}
+
+	var messages []Message
+	if err := codec(d).Decode(CookieName, cookie.Value, &messages); err != nil {
+		return nil
+	}
+
+	return messages
+}
+
+func write(w http.ResponseWriter, d dependencies, messages []Message) error {
+	for len(messages) > 0 {
+		encoded, err := codec(d).Encode(CookieName, messages)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if len(encoded) <= maxCookieBytes {
+			http.SetCookie(w, &http.Cookie{
+				Name:     CookieName,
+				Value:    encoded,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+				Expires:  time.Now().Add(5 * time.Minute),
+			})
+			return nil
+		}
+
+		// Cookie would be too large - evict the oldest message and retry.
+		messages = messages[1:]
+	}
+
+	// Nothing left to store (or nothing was ever added) - clear any stale cookie.
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	return nil
+}
+
+// Add appends message to the pending flash cookie for the current response,
+// preserving any messages already queued earlier in the same request chain
+// (e.g. a validation-failure message followed by a redirect-hook message).
+func Add(w http.ResponseWriter, r *http.Request, d dependencies, message Message) error {
+	messages := append(read(r, d), message)
+	return write(w, d, messages)
+}
+
+// Consume reads and clears the pending flash messages, returning them in the
+// order they were added (oldest first).
+func Consume(w http.ResponseWriter, r *http.Request, d dependencies) []Message {
+	messages := read(r, d)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	return messages
+}
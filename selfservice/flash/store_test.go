@@ -0,0 +1,82 @@
+package flash_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flash"
+)
+
+func TestFlash(t *testing.T) {
+	_, reg := internal.NewRegistryDefault(t)
+	viper.Set(configuration.ViperKeySecretsDefault, []string{"something-something-something-something-32"})
+
+	t.Run("case=add is readable once and then cleared", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		require.NoError(t, flash.Add(w, r, reg, flash.Message{Level: "success", ID: "settings_profile_updated"}))
+
+		r2 := httptest.NewRequest("GET", "/", nil)
+		for _, c := range w.Result().Cookies() {
+			r2.AddCookie(c)
+		}
+
+		w2 := httptest.NewRecorder()
+		messages := flash.Consume(w2, r2, reg)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "settings_profile_updated", messages[0].ID)
+
+		var cleared bool
+		for _, c := range w2.Result().Cookies() {
+			if c.Name == flash.CookieName {
+				cleared = c.MaxAge < 0
+			}
+		}
+		assert.True(t, cleared, "cookie should be cleared on read")
+
+		r3 := httptest.NewRequest("GET", "/", nil)
+		w3 := httptest.NewRecorder()
+		assert.Empty(t, flash.Consume(w3, r3, reg), "message should not be readable a second time")
+	})
+
+	t.Run("case=oversized queue evicts oldest messages first", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		var w *httptest.ResponseRecorder
+
+		// Each Add gets a fresh request carrying only the cookie the previous
+		// Add wrote, the way a browser would send it back.
+		for i := 0; i < 200; i++ {
+			w = httptest.NewRecorder()
+			require.NoError(t, flash.Add(w, r, reg, flash.Message{
+				Level: "info",
+				ID:    "noise",
+				Args:  map[string]interface{}{"i": i, "padding": strings.Repeat("x", 64)},
+			}))
+			r = httptest.NewRequest("GET", "/", nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+		}
+
+		for _, c := range w.Result().Cookies() {
+			if c.Name == flash.CookieName {
+				assert.LessOrEqual(t, len(c.Value), 4096+256)
+			}
+		}
+
+		w2 := httptest.NewRecorder()
+		messages := flash.Consume(w2, r, reg)
+		require.NotEmpty(t, messages)
+		last := messages[len(messages)-1].Args["i"]
+		assert.EqualValues(t, 199, last, "newest message should have survived eviction")
+	})
+}
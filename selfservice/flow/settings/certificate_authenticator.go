@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+)
+
+// CertificateAuthenticator decides whether a request is "freshly privileged"
+// because it was presented with a trusted mTLS client certificate, rather
+// than because the identity recently completed an interactive login. This is
+// the headless/hardware-token equivalent of the privileged_session_max_age
+// check performed by the login-redirect flow.
+type CertificateAuthenticator struct {
+	d certificateAuthenticatorDependencies
+}
+
+type certificateAuthenticatorDependencies interface {
+	IdentityPool() identity.Pool
+}
+
+func NewCertificateAuthenticator(d certificateAuthenticatorDependencies) *CertificateAuthenticator {
+	return &CertificateAuthenticator{d: d}
+}
+
+// caPool parses the configured PEM bundle on every call so that rotating the
+// bundle in the config source (e.g. a mounted secret) takes effect without a
+// restart.
+func (a *CertificateAuthenticator) caPool() (*x509.CertPool, error) {
+	bundle := viper.GetString(configuration.ViperKeySelfServiceSettingsCertificateAuthenticatorCABundle)
+	if bundle == "" {
+		return nil, errors.New("no certificate_authenticator.ca_bundle configured")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, errors.New("unable to parse certificate_authenticator.ca_bundle as PEM")
+	}
+
+	return pool, nil
+}
+
+// IsPrivileged returns true if r carries a client certificate, verified
+// against the configured CA bundle, whose Subject/SAN matches i's own
+// CredentialsTypeCertificate credential (identity.CredentialsCertificateConfig)
+// - not a separate, identity-agnostic allow-list. It never errors for "no
+// certificate presented", "identity has no certificate credential", or
+// "certificate does not verify against the CA bundle" - those are simply not
+// privileged, not configuration failures - but it does
+// return an error when the CA bundle itself is misconfigured, or the stored
+// credential config doesn't parse, so operators notice at request time
+// rather than silently falling back to the login redirect.
+func (a *CertificateAuthenticator) IsPrivileged(r *http.Request, i *identity.Identity) (bool, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false, nil
+	}
+
+	creds, ok := i.Credentials[identity.CredentialsTypeCertificate]
+	if !ok {
+		return false, nil
+	}
+
+	var conf identity.CredentialsCertificateConfig
+	if err := json.Unmarshal(creds.Config, &conf); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	pool, err := a.caPool()
+	if err != nil {
+		return false, err
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, ic := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(ic)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return false, nil
+	}
+
+	if conf.Subject != "" && conf.Subject != cert.Subject.String() {
+		return false, nil
+	}
+	if conf.SAN != "" && !certificateHasSAN(cert, conf.SAN) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func certificateHasSAN(cert *x509.Certificate, san string) bool {
+	for _, name := range cert.DNSNames {
+		if name == san {
+			return true
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if email == san {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == san {
+			return true
+		}
+	}
+	return false
+}
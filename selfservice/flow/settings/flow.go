@@ -0,0 +1,57 @@
+package settings
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/selfservice/form"
+)
+
+// FlowMethod is the rendered form for a single settings strategy (e.g.
+// "profile", "oidc") inside a Flow.
+type FlowMethod struct {
+	Config *form.HTMLForm `json:"config"`
+}
+
+// Flow represents an in-progress settings request. It is created on GET
+// PublicPath and consulted/updated by every strategy's POST handler until
+// one of them reports success.
+//
+// swagger:model settingsFlow
+type Flow struct {
+	ID         uuid.UUID              `json:"id"`
+	IdentityID uuid.UUID              `json:"-"`
+	RequestURL string                 `json:"request_url"`
+	Methods    map[string]*FlowMethod `json:"methods"`
+	IssuedAt   time.Time              `json:"issued_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+
+	// UpdateSuccessful is true once a strategy has successfully persisted a
+	// change for this flow.
+	UpdateSuccessful bool `json:"update_successful"`
+
+	// PendingVerification is set instead of UpdateSuccessful when a scoped
+	// update requires a verification challenge to complete before the
+	// change is persisted - see Scope/ScopeRule.
+	PendingVerification bool `json:"pending_verification,omitempty"`
+
+	// CSRFToken is minted once per Flow and rendered into every strategy's
+	// form as a form.CSRFTokenName field. It never leaves the Flow's own
+	// JSON representation at the top level - only the forms reflect it back
+	// to the browser - so it is not itself part of the public API payload.
+	CSRFToken string `json:"-"`
+}
+
+// FlowPersister stores and retrieves in-progress settings flows.
+type FlowPersister interface {
+	CreateSettingsFlow(ctx context.Context, r *http.Request, f *Flow) error
+	GetSettingsFlow(ctx context.Context, id uuid.UUID) (*Flow, error)
+	UpdateSettingsFlow(ctx context.Context, f *Flow) error
+}
+
+type FlowPersistenceProvider interface {
+	SettingsFlowPersister() FlowPersister
+}
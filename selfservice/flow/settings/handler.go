@@ -0,0 +1,256 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flash"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+// PublicPath initiates a settings Flow and redirects the browser to the
+// settings UI.
+const PublicPath = "/self-service/browser/flows/requests/settings"
+
+// PublicSettingsProfilePath is where the "profile" strategy's form POSTs to.
+const PublicSettingsProfilePath = "/self-service/browser/flows/settings/profile"
+
+type handlerDependencies interface {
+	configuration.Provider
+	identity.PoolProvider
+	session.ManagerProvider
+	StrategiesProvider
+	FlowPersistenceProvider
+}
+
+// Handler serves the settings flow lifecycle: starting a flow, fetching one
+// by request ID, and dispatching a submitted form to the matching Strategy.
+// It owns the one piece of cross-cutting behaviour every strategy needs:
+// deciding whether the current request is privileged enough to touch a
+// sudo-gated field.
+type Handler struct {
+	d handlerDependencies
+}
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+// RegisterPublicRoutes mounts the settings flow lifecycle and, since every
+// settings outcome (success, failure, pending verification) is reported
+// through a flash message, the flash read endpoint alongside it.
+func (h *Handler) RegisterPublicRoutes(public *httprouter.Router) {
+	public.GET(PublicPath, h.initFlow)
+	public.GET(PublicPath+"/requests", h.fetchFlow)
+	public.POST(PublicSettingsProfilePath, h.submitProfile)
+
+	flash.NewHandler(h.d).RegisterPublicRoutes(public)
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *httprouter.Router) {
+	admin.GET(PublicPath+"/requests", h.fetchFlow)
+}
+
+func (h *Handler) initFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sess, err := h.d.SessionManager().FetchFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	f := &Flow{
+		ID:         x.NewUUID(),
+		IdentityID: sess.Identity.ID,
+		RequestURL: scheme + "://" + r.Host + r.URL.String(),
+		Methods:    map[string]*FlowMethod{},
+		IssuedAt:   time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+		CSRFToken:  x.NewUUID().String(),
+	}
+
+	for _, strategy := range h.d.SettingsStrategies() {
+		if err := strategy.PopulateSettingsMethod(r, sess.Identity, f); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, method := range f.Methods {
+		method.Config.Fields = append(form.Fields{{
+			Name:  form.CSRFTokenName,
+			Type:  "hidden",
+			Value: f.CSRFToken,
+		}}, method.Config.Fields...)
+	}
+
+	if err := h.d.SettingsFlowPersister().CreateSettingsFlow(r.Context(), r, f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, viper.GetString(configuration.ViperKeyURLsSettingsUI)+"?request="+f.ID.String(), http.StatusFound)
+}
+
+func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	id, err := uuid.FromString(r.URL.Query().Get("request"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := h.d.SettingsFlowPersister().GetSettingsFlow(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	i, err := h.d.IdentityPool().GetIdentity(r.Context(), f.IdentityID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, &settingsFlowPayload{Flow: f, Identity: i})
+}
+
+// settingsFlowPayload is the JSON shape returned by fetchFlow: the Flow
+// itself plus the identity it belongs to, which every settings SDK client
+// needs in order to render each field's currently stored value.
+type settingsFlowPayload struct {
+	*Flow
+	Identity *identity.Identity `json:"identity"`
+}
+
+// submitProfile is the single POST entry point the "profile" strategy's
+// form action points to. It is responsible for:
+//   - requiring a session (401 if missing)
+//   - requiring privileged re-authentication for any scope the submission
+//     touches that demands it - either via a recent interactive login or,
+//     per chunk0-1, a verified mTLS client certificate
+//   - dispatching to the StrategyTraits strategy and running after-hooks
+func (h *Handler) submitProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sess, err := h.d.SessionManager().FetchFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.FromString(r.URL.Query().Get("request"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := h.d.SettingsFlowPersister().GetSettingsFlow(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := requireCSRFToken(r, f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strategy, ok := h.d.SettingsStrategies().Get(StrategyTraitsID)
+	if !ok {
+		http.Error(w, errors.Errorf("no strategy registered for %q", StrategyTraitsID).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	profileStrategy, ok := strategy.(*StrategyTraits)
+	if !ok {
+		http.Error(w, errors.Errorf("strategy registered for %q is not a *StrategyTraits", StrategyTraitsID).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if profileStrategy.TouchesSudoScope(r, sess.Identity) {
+		if err := RequirePrivilegedSession(h.d, r, sess); err != nil {
+			if err != ErrPrivilegedSessionRequired {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			h.redirectToLogin(w, r, f)
+			return
+		}
+	}
+
+	updated, formErr := profileStrategy.Settle(r, sess.Identity, f)
+	if formErr != nil {
+		_ = flash.Add(w, r, h.d, flash.Message{Level: "error", ID: "settings_profile_update_failed"})
+		writeJSON(w, f)
+		return
+	}
+
+	if f.PendingVerification {
+		_ = flash.Add(w, r, h.d, flash.Message{Level: "info", ID: "settings_pending_verification"})
+		writeJSON(w, f)
+		return
+	}
+
+	f.UpdateSuccessful = true
+	if err := h.d.IdentityPool().UpdateIdentity(r.Context(), updated); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.d.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = flash.Add(w, r, h.d, flash.Message{Level: "success", ID: "settings_profile_updated"})
+	if h.runAfterHooks(w, r) {
+		return
+	}
+	writeJSON(w, f)
+}
+
+func (h *Handler) redirectToLogin(w http.ResponseWriter, r *http.Request, f *Flow) {
+	RedirectToLogin(w, r, f)
+}
+
+// requireCSRFToken parses r's form body and checks that it carries f's CSRF
+// token, the one check every state-changing settings endpoint - whichever
+// strategy handles it - must perform before mutating anything.
+func requireCSRFToken(r *http.Request, f *Flow) error {
+	if err := r.ParseForm(); err != nil {
+		return errors.WithStack(err)
+	}
+	if f.CSRFToken == "" || r.PostForm.Get(form.CSRFTokenName) != f.CSRFToken {
+		return errors.New("csrf token missing or incorrect")
+	}
+	return nil
+}
+
+// runAfterHooks redirects to the configured after-settings-update hook, if
+// one is set, and reports whether it did - a caller still owes the request
+// a response (writeJSON(w, f)) when it didn't.
+func (h *Handler) runAfterHooks(w http.ResponseWriter, r *http.Request) bool {
+	hook := viper.GetString(configuration.ViperKeySelfServiceSettingsAfterConfig + "." + StrategyTraitsID + ".hooks.0.config.default_redirect_url")
+	if hook == "" {
+		return false
+	}
+	http.Redirect(w, r, hook, http.StatusFound)
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,74 @@
+package settings_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flash"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/x"
+)
+
+// TestSettingsFlashEndToEnd confirms the flashes endpoint is actually mounted
+// by Handler.RegisterPublicRoutes and that a successful settings update is
+// readable there exactly once, addressing the gap where flash.Add had no
+// reachable consumer.
+func TestSettingsFlashEndToEnd(t *testing.T) {
+	_, reg := internal.NewRegistryDefault(t)
+	viper.Set(configuration.ViperKeyDefaultIdentityTraitsSchemaURL, "file://./stub/identity.schema.json")
+	viper.Set(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter, "5m")
+	viper.Set(configuration.ViperKeySecretsDefault, []string{"something-something-something-something-32"})
+
+	primaryIdentity := identity.Identity{
+		ID: x.NewUUID(),
+		Credentials: map[identity.CredentialsType]identity.Credentials{
+			"password": {Type: "password", Identifiers: []string{"john@doe.com"}, Config: json.RawMessage(`{"hashed_password":"foo"}`)},
+		},
+		Traits:         identity.Traits(`{"email":"john@doe.com","stringy":"foobar"}`),
+		TraitsSchemaID: configuration.DefaultIdentityTraitsSchemaID,
+	}
+	publicTS, _ := testhelpers.NewSettingsAPIServer(t, reg, []identity.Identity{primaryIdentity})
+
+	primaryUser := testhelpers.NewSessionClient(t, publicTS.URL+"/sessions/set/0")
+
+	config := testhelpers.GetSettingsMethodConfig(t, primaryUser, publicTS, settings.StrategyTraitsID)
+	values := testhelpers.SDKFormFieldsToURLValues(config.Fields)
+	values.Set("traits.stringy", "bazbar")
+	_, response := testhelpers.SettingsSubmitForm(t, config, primaryUser, values)
+	require.NotNil(t, response.Payload)
+
+	res, err := primaryUser.Get(publicTS.URL + flash.PublicFlashesPath)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var messages []flash.Message
+	require.NoError(t, json.Unmarshal(body, &messages))
+	require.NotEmpty(t, messages, "the profile update's flash message should be readable")
+	assert.Equal(t, "settings_profile_updated", messages[0].ID)
+
+	res2, err := primaryUser.Get(publicTS.URL + flash.PublicFlashesPath)
+	require.NoError(t, err)
+	defer res2.Body.Close()
+
+	body2, err := ioutil.ReadAll(res2.Body)
+	require.NoError(t, err)
+
+	var second []flash.Message
+	require.NoError(t, json.Unmarshal(body2, &second))
+	assert.Empty(t, second, "the flash message must not be readable a second time")
+}
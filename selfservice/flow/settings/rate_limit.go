@@ -0,0 +1,30 @@
+package settings
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// UpdateCounter tracks how many times each identity has updated a given
+// scope today, backing ScopeRule.MaxUpdatesPerDay.
+type UpdateCounter interface {
+	// CountUpdatesToday returns how many times scope has already been
+	// updated for identityID today.
+	CountUpdatesToday(ctx context.Context, identityID uuid.UUID, scope Scope) (int, error)
+
+	// RecordUpdate records one more update to scope for identityID,
+	// counting towards today's total.
+	RecordUpdate(ctx context.Context, identityID uuid.UUID, scope Scope) error
+}
+
+// UpdateCounterProvider is embedded by any dependencies interface that needs
+// to enforce ScopeRule.MaxUpdatesPerDay.
+type UpdateCounterProvider interface {
+	SettingsUpdateCounter() UpdateCounter
+}
+
+// ErrMaxUpdatesPerDayExceeded is returned when a scope's configured
+// MaxUpdatesPerDay would be exceeded by the current submission.
+var ErrMaxUpdatesPerDayExceeded = errors.New("this field has already been updated the maximum number of times allowed today")
@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Scope is a value from a JSON-schema property's `ory.sh/kratos.scopes`
+// vocabulary, e.g. "profile:basic" or "profile:sensitive". Properties
+// without an explicit scopes array fall back to DefaultScope.
+type Scope string
+
+// DefaultScope is assigned to any schema property that does not declare a
+// `scopes` array, preserving today's behaviour (no sudo, no verification,
+// unlimited updates) for schemas that haven't opted into the vocabulary yet.
+const DefaultScope Scope = "profile:default"
+
+// ScopeRule is one entry of the selfservice.settings.scopes config map. The
+// strictest matching rule across every scope touched by a request is the one
+// that is enforced - see StrictestRule.
+type ScopeRule struct {
+	// RequireSudo mirrors today's hard-coded "protected traits" check, but
+	// per scope instead of for the whole traits object.
+	RequireSudo bool `json:"require_sudo" mapstructure:"require_sudo"`
+
+	// RequireVerification, if set to anything other than "" or "none",
+	// queues a verification challenge
+	// for the new value via the existing verification subsystem before the
+	// update is persisted.
+	RequireVerification string `json:"require_verification" mapstructure:"require_verification"`
+
+	// MaxUpdatesPerDay rate-limits how often this scope's fields may be
+	// changed. Zero means unlimited.
+	MaxUpdatesPerDay int `json:"max_updates_per_day" mapstructure:"max_updates_per_day"`
+}
+
+// stricter reports whether a is a stricter requirement than b, used to fold
+// a set of matching rules down to the single strictest one.
+func (a ScopeRule) stricter(b ScopeRule) bool {
+	if a.RequireSudo != b.RequireSudo {
+		return a.RequireSudo
+	}
+	aVerify := a.RequireVerification != "" && a.RequireVerification != "none"
+	bVerify := b.RequireVerification != "" && b.RequireVerification != "none"
+	if aVerify != bVerify {
+		return aVerify
+	}
+	if a.MaxUpdatesPerDay != 0 && (b.MaxUpdatesPerDay == 0 || a.MaxUpdatesPerDay < b.MaxUpdatesPerDay) {
+		return true
+	}
+	return false
+}
+
+// StrictestRule folds rules down to the single strictest one: sudo required
+// beats not required, verification required beats none, and the tightest
+// update-rate cap wins. Scopes with no configured rule are treated as the
+// zero-value ScopeRule (today's behaviour).
+func StrictestRule(rules map[Scope]ScopeRule, touched []Scope) ScopeRule {
+	var strictest ScopeRule
+	for _, scope := range touched {
+		rule := rules[scope]
+		if rule.stricter(strictest) {
+			strictest = rule
+		}
+	}
+	return strictest
+}
+
+// schemaScopes walks a compiled JSON-schema document (as raw JSON) and
+// returns, for every "traits.<path>" property, the `ory.sh/kratos.scopes`
+// array declared on it - or [DefaultScope] if none was declared.
+func schemaScopes(schema json.RawMessage) map[string][]Scope {
+	out := map[string][]Scope{}
+
+	properties := gjson.GetBytes(schema, "properties.traits.properties")
+	properties.ForEach(func(key, value gjson.Result) bool {
+		path := "traits." + key.String()
+
+		scopes := value.Get(`ory\.sh/kratos.scopes`)
+		if !scopes.Exists() {
+			out[path] = []Scope{DefaultScope}
+			return true
+		}
+
+		var parsed []Scope
+		scopes.ForEach(func(_, s gjson.Result) bool {
+			parsed = append(parsed, Scope(s.String()))
+			return true
+		})
+		if len(parsed) == 0 {
+			parsed = []Scope{DefaultScope}
+		}
+		out[path] = parsed
+
+		return true
+	})
+
+	return out
+}
+
+// TouchedScopes diffs submitted form values against the stored traits and
+// returns the set of scopes touched by whatever the caller actually
+// submitted - untouched fields (and therefore their scopes) are left alone,
+// which is what makes partial updates possible: a client only has to submit
+// the fields whose scope it currently satisfies.
+func TouchedScopes(schema json.RawMessage, stored json.RawMessage, submitted map[string]string) []Scope {
+	scopesByField := schemaScopes(schema)
+
+	seen := map[Scope]struct{}{}
+	var touched []Scope
+
+	for field, newValue := range submitted {
+		oldValue := gjson.GetBytes(stored, strings.TrimPrefix(field, "traits.")).String()
+		if oldValue == newValue {
+			continue
+		}
+
+		for _, scope := range scopesByField[field] {
+			if _, ok := seen[scope]; ok {
+				continue
+			}
+			seen[scope] = struct{}{}
+			touched = append(touched, scope)
+		}
+	}
+
+	return touched
+}
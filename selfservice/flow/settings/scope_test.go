@@ -0,0 +1,69 @@
+package settings_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/selfservice/flow/settings"
+)
+
+var scopedSchema = json.RawMessage(`{
+	"properties": {
+		"traits": {
+			"properties": {
+				"email": {"type": "string", "ory.sh/kratos": {"scopes": ["profile:sensitive"]}},
+				"should_big_number": {"type": "integer", "ory.sh/kratos": {"scopes": ["profile:basic"]}}
+			}
+		}
+	}
+}`)
+
+func TestTouchedScopes(t *testing.T) {
+	stored := json.RawMessage(`{"email":"john@doe.com","should_big_number":2048}`)
+
+	t.Run("case=updating a profile:basic field does not touch profile:sensitive", func(t *testing.T) {
+		touched := settings.TouchedScopes(scopedSchema, stored, map[string]string{
+			"traits.should_big_number": "4096",
+		})
+		assert.Equal(t, []settings.Scope{"profile:basic"}, touched)
+	})
+
+	t.Run("case=updating email touches profile:sensitive", func(t *testing.T) {
+		touched := settings.TouchedScopes(scopedSchema, stored, map[string]string{
+			"traits.email": "not-john-doe@mail.com",
+		})
+		assert.Equal(t, []settings.Scope{"profile:sensitive"}, touched)
+	})
+
+	t.Run("case=resubmitting the same value touches nothing", func(t *testing.T) {
+		touched := settings.TouchedScopes(scopedSchema, stored, map[string]string{
+			"traits.email": "john@doe.com",
+		})
+		assert.Empty(t, touched)
+	})
+}
+
+func TestStrictestRule(t *testing.T) {
+	rules := map[settings.Scope]settings.ScopeRule{
+		"profile:basic":     {RequireSudo: false, RequireVerification: "none"},
+		"profile:sensitive": {RequireSudo: true, RequireVerification: "email"},
+	}
+
+	t.Run("case=big-number update under profile:basic needs no sudo", func(t *testing.T) {
+		rule := settings.StrictestRule(rules, []settings.Scope{"profile:basic"})
+		assert.False(t, rule.RequireSudo)
+	})
+
+	t.Run("case=email update under profile:sensitive still requires sudo", func(t *testing.T) {
+		rule := settings.StrictestRule(rules, []settings.Scope{"profile:sensitive"})
+		assert.True(t, rule.RequireSudo)
+		assert.Equal(t, "email", rule.RequireVerification)
+	})
+
+	t.Run("case=touching both scopes applies the strictest rule", func(t *testing.T) {
+		rule := settings.StrictestRule(rules, []settings.Scope{"profile:basic", "profile:sensitive"})
+		assert.True(t, rule.RequireSudo)
+	})
+}
@@ -0,0 +1,61 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/identity"
+)
+
+// Strategy is implemented by every settings method (profile, oidc, ...). For
+// submissions to PublicSettingsProfilePath the Handler dispatches to the
+// profile strategy and runs the shared after-hooks (redirects, flash
+// messages) itself - that strategy only reports success or a form error
+// back. Routes a strategy mounts through RegisterPublicRoutes (e.g. oidc's
+// link/unlink endpoints) are served by the strategy itself, which writes
+// its own response and flash messages.
+type Strategy interface {
+	// ID is the method name this strategy is registered under, e.g.
+	// StrategyTraitsID or StrategyOIDCID.
+	ID() string
+
+	// RegisterPublicRoutes mounts whatever additional public endpoints this
+	// strategy needs beyond the shared PublicSettingsProfilePath dispatch,
+	// e.g. the oidc link/unlink callbacks.
+	RegisterPublicRoutes(router *httprouter.Router)
+
+	// PopulateSettingsMethod fills in f.Methods[s.ID()] with the form this
+	// strategy wants rendered for identity i.
+	PopulateSettingsMethod(r *http.Request, i *identity.Identity, f *Flow) error
+}
+
+// StrategiesProvider exposes every registered settings Strategy, keyed by
+// ID(), so the Handler can dispatch POST PublicSettingsProfilePath to the
+// right one and so tests/registries can look individual strategies up by
+// name.
+type StrategiesProvider interface {
+	SettingsStrategies() Strategies
+}
+
+type Strategies []Strategy
+
+func (s Strategies) Get(id string) (Strategy, bool) {
+	for _, strategy := range s {
+		if strategy.ID() == id {
+			return strategy, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterPublicRoutes mounts every strategy's additional public routes.
+func (s Strategies) RegisterPublicRoutes(router *httprouter.Router) {
+	for _, strategy := range s {
+		strategy.RegisterPublicRoutes(router)
+	}
+}
+
+type HandlerProvider interface {
+	SettingsHandler() *Handler
+}
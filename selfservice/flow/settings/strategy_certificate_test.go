@@ -0,0 +1,78 @@
+package settings_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/x"
+)
+
+// TestStrategyTraitsCertificateSudo mirrors "should update protected field
+// with sudo mode" but proves privileged status with a client certificate
+// instead of bouncing through the login UI.
+func TestStrategyTraitsCertificateSudo(t *testing.T) {
+	_, reg := internal.NewRegistryDefault(t)
+	viper.Set(configuration.ViperKeyDefaultIdentityTraitsSchemaURL, "file://./stub/identity.schema.json")
+	viper.Set(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter, "1ns")
+
+	caCert, caKey := testhelpers.NewTestCA(t)
+	clientCert := testhelpers.NewTestClientCert(t, caCert, caKey, "CN=agent-1")
+
+	primaryIdentity := &identity.Identity{
+		ID: x.NewUUID(),
+		Credentials: map[identity.CredentialsType]identity.Credentials{
+			"password":                            {Type: "password", Identifiers: []string{"john@doe.com"}, Config: json.RawMessage(`{"hashed_password":"foo"}`)},
+			identity.CredentialsTypeCertificate: {Type: identity.CredentialsTypeCertificate, Config: identity.CredentialsCertificateConfig{Subject: "CN=agent-1"}.Marshal()},
+		},
+		Traits:         identity.Traits(`{"email":"john@doe.com","stringy":"foobar","booly":false,"numby":2.5,"should_long_string":"asdfasdfasdfasdfasfdasdfasdfasdf","should_big_number":2048}`),
+		TraitsSchemaID: configuration.DefaultIdentityTraitsSchemaID,
+	}
+
+	publicTS, _ := testhelpers.NewSettingsAPIServer(t, reg, []identity.Identity{*primaryIdentity})
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+	viper.Set(configuration.ViperKeySelfServiceSettingsCertificateAuthenticatorCABundle, testhelpers.EncodeCertPEM(caCert))
+
+	tlsTS := httptest.NewUnstartedServer(publicTS.Config.Handler)
+	tlsTS.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	tlsTS.StartTLS()
+	defer tlsTS.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		Certificates:       []tls.Certificate{clientCert},
+		InsecureSkipVerify: true,
+	}}}
+
+	primaryUser := testhelpers.NewSessionClientWithHTTPClient(t, tlsTS.URL+"/sessions/set/0", client)
+
+	loginCalled := false
+	loginTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loginCalled = true
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer loginTS.Close()
+	viper.Set(configuration.ViperKeyURLsLogin, loginTS.URL+"/login")
+
+	f := testhelpers.GetSettingsMethodConfig(t, primaryUser, tlsTS, settings.StrategyTraitsID)
+	values := testhelpers.SDKFormFieldsToURLValues(f.Fields)
+	values.Set("traits.email", "not-john-doe@mail.com")
+	actual, response := testhelpers.SettingsSubmitForm(t, f, primaryUser, values)
+
+	require.False(t, loginCalled, "client-certificate re-authentication should not fall back to the login UI: %s", actual)
+	assert.True(t, response.Payload.UpdateSuccessful != nil && *response.Payload.UpdateSuccessful, "%s", actual)
+}
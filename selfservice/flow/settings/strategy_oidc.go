@@ -0,0 +1,506 @@
+package settings
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flash"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+// oidcStateTTL bounds how long a handleLink redirect can sit at the
+// provider's consent screen before its state is no longer accepted back.
+const oidcStateTTL = 15 * time.Minute
+
+// oidcState is what a "state" query param actually buys the caller: nothing
+// on its own, since it's just a lookup key into this server-side record.
+// Binding it to FlowID/IdentityID here - rather than trusting a client-
+// supplied "provider:flowID:identityID" string, as this handler used to -
+// is what stops a forged state from linking a provider account to whichever
+// identity an attacker names.
+type oidcState struct {
+	Provider   string
+	FlowID     uuid.UUID
+	IdentityID uuid.UUID
+	ExpiresAt  time.Time
+}
+
+// StrategyOIDCID is the settings equivalent of the login/registration oidc
+// strategy: it lets an already-authenticated identity attach ("link") or
+// detach ("unlink") a social sign-in provider without going through a
+// separate registration flow.
+const StrategyOIDCID = "oidc"
+
+// ErrLastCredentialsLeftEmpty is returned when an unlink would leave the
+// identity without any credentials, which would make it impossible to ever
+// log back in.
+var ErrLastCredentialsLeftEmpty = errors.New("unlinking this provider would leave the identity without any credentials")
+
+type oidcDependencies interface {
+	configuration.Provider
+	identity.PoolProvider
+	session.ManagerProvider
+	FlowPersistenceProvider
+	OIDCProvidersProvider
+}
+
+// OIDCProviderConfig is one entry of the oidc strategy's existing provider
+// configuration, reused here so settings can link/unlink exactly the
+// providers login/registration already know about. IssuerURL is what lets
+// handleCallback verify a provider's id_token the same way login/
+// registration does - resolving the provider's discovery document and
+// JWKS - rather than trusting an unverified "sub" claim.
+type OIDCProviderConfig struct {
+	ID           string   `mapstructure:"id"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	IssuerURL    string   `mapstructure:"issuer_url"`
+	AuthURL      string   `mapstructure:"auth_url"`
+	TokenURL     string   `mapstructure:"token_url"`
+	Scope        []string `mapstructure:"scope"`
+}
+
+type OIDCProvidersProvider interface {
+	// OIDCProviders returns the same provider list the login/registration
+	// oidc strategy is configured with.
+	OIDCProviders() []OIDCProviderConfig
+}
+
+// StrategyOIDC mounts under PublicPath and renders a "Link"/"Unlink" form
+// field pair per configured OIDC provider. Both operations are privileged:
+// both handlers call RequirePrivilegedSession before touching credentials,
+// the same gate StrategyTraits applies to protected traits.
+type StrategyOIDC struct {
+	d oidcDependencies
+
+	statesMutex sync.Mutex
+	states      map[string]oidcState
+
+	verifiersMutex sync.Mutex
+	verifiers      map[string]*oidc.IDTokenVerifier
+}
+
+func NewStrategyOIDC(d oidcDependencies) *StrategyOIDC {
+	return &StrategyOIDC{d: d, states: map[string]oidcState{}, verifiers: map[string]*oidc.IDTokenVerifier{}}
+}
+
+// mintState generates a fresh, unguessable state token bound to flowID and
+// identityID and remembers it server-side, opportunistically sweeping out
+// anything that's already expired while it's holding the lock.
+func (s *StrategyOIDC) mintState(provider string, flowID, identityID uuid.UUID) string {
+	s.statesMutex.Lock()
+	defer s.statesMutex.Unlock()
+
+	now := time.Now()
+	for token, rec := range s.states {
+		if now.After(rec.ExpiresAt) {
+			delete(s.states, token)
+		}
+	}
+
+	token := x.NewUUID().String()
+	s.states[token] = oidcState{Provider: provider, FlowID: flowID, IdentityID: identityID, ExpiresAt: now.Add(oidcStateTTL)}
+	return token
+}
+
+// consumeState looks up and removes (one-time use) the record behind token,
+// refusing it if it doesn't exist or has expired.
+func (s *StrategyOIDC) consumeState(token string) (oidcState, bool) {
+	s.statesMutex.Lock()
+	defer s.statesMutex.Unlock()
+
+	rec, ok := s.states[token]
+	delete(s.states, token)
+	if !ok || time.Now().After(rec.ExpiresAt) {
+		return oidcState{}, false
+	}
+	return rec, true
+}
+
+func (s *StrategyOIDC) ID() string {
+	return StrategyOIDCID
+}
+
+func (s *StrategyOIDC) RegisterPublicRoutes(router *httprouter.Router) {
+	router.GET(PublicPath+"/methods/oidc/link/:provider", s.handleLink)
+	router.GET(PublicPath+"/methods/oidc/callback/:provider", s.handleCallback)
+	router.POST(PublicPath+"/methods/oidc/unlink/:provider", s.handleUnlink)
+}
+
+// PopulateSettingsMethod adds a methods.oidc.config entry enumerating every
+// configured provider, marking which ones the identity already has linked
+// (and with what identifier) so the UI can render "Connected as
+// john@gmail.com - Unlink" versus "Link <provider>". Unlike every other
+// strategy's fields, a provider's link (GET) and unlink (POST) targets don't
+// share the form's own Action/Method - RegisterPublicRoutes never mounts
+// anything at that form-level Action - so each field carries its own
+// Action/Method pointing at the route that actually serves it.
+func (s *StrategyOIDC) PopulateSettingsMethod(r *http.Request, i *identity.Identity, f *Flow) error {
+	linked := LinkedProviders(i)
+
+	fields := form.Fields{}
+	for _, provider := range s.d.OIDCProviders() {
+		if subject, ok := linked[provider.ID]; ok {
+			fields = append(fields, form.Field{
+				Name:   "unlink." + provider.ID,
+				Type:   "submit",
+				Value:  subject,
+				Action: PublicPath + "/methods/oidc/unlink/" + provider.ID + "?request=" + f.ID.String(),
+				Method: "POST",
+			})
+		} else {
+			fields = append(fields, form.Field{
+				Name:   "link." + provider.ID,
+				Type:   "submit",
+				Action: PublicPath + "/methods/oidc/link/" + provider.ID + "?request=" + f.ID.String(),
+				Method: "GET",
+			})
+		}
+	}
+
+	f.Methods[StrategyOIDCID] = &FlowMethod{Config: &form.HTMLForm{
+		Action: PublicPath + "/methods/oidc",
+		Method: "POST",
+		Fields: fields,
+	}}
+
+	return nil
+}
+
+// handleLink requires a privileged session for the request's flow and, if
+// granted, redirects to the provider's OAuth2 authorization endpoint. The
+// OAuth2 "state" parameter is an unguessable token minted by mintState and
+// bound server-side to this flow and identity - handleCallback trusts
+// nothing the client supplies beyond that token.
+func (s *StrategyOIDC) handleLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	sess, f, err := s.requireSudoFlow(r)
+	if err != nil {
+		s.handleSudoFailure(w, r, f, err)
+		return
+	}
+
+	provider, ok := s.provider(ps.ByName("provider"))
+	if !ok {
+		http.Error(w, "unknown provider", http.StatusBadRequest)
+		return
+	}
+
+	conf := s.oauth2Config(provider)
+	state := s.mintState(provider.ID, f.ID, sess.Identity.ID)
+	http.Redirect(w, r, conf.AuthCodeURL(state), http.StatusFound)
+}
+
+// handleCallback exchanges the authorization code for the provider's
+// subject identifier and links it to the identity that initiated the
+// matching handleLink call. It authenticates the caller two ways before
+// doing so: the state token must be one handleLink actually minted (not
+// merely well-formed), and the request's own session must belong to the
+// same identity that state was minted for - a forged or replayed state from
+// a different session is rejected rather than trusted to name the identity
+// to link.
+func (s *StrategyOIDC) handleCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	sess, err := s.d.SessionManager().FetchFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rec, ok := s.consumeState(r.URL.Query().Get("state"))
+	if !ok {
+		http.Error(w, "invalid or expired oidc state", http.StatusBadRequest)
+		return
+	}
+
+	if rec.IdentityID != sess.Identity.ID {
+		http.Error(w, "oidc state does not belong to the current session", http.StatusForbidden)
+		return
+	}
+
+	provider, ok := s.provider(rec.Provider)
+	if !ok {
+		http.Error(w, "unknown provider", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.oauth2Config(provider).Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	subject, err := s.verifiedSubject(r.Context(), provider, rawIDToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := s.LinkCredentials(r.Context(), sess.Identity, rec.Provider, subject); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, sess.Identity)
+}
+
+func (s *StrategyOIDC) handleUnlink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	sess, f, err := s.requireSudoFlow(r)
+	if err != nil {
+		s.handleSudoFailure(w, r, f, err)
+		return
+	}
+
+	if err := requireCSRFToken(r, f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	provider := ps.ByName("provider")
+
+	if err := s.UnlinkCredentials(sess.Identity, provider); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err := s.d.IdentityPool().UpdateIdentity(r.Context(), sess.Identity); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f.UpdateSuccessful = true
+	_ = flash.Add(w, r, s.d, flash.Message{Level: "success", ID: "settings_oidc_unlinked", Args: map[string]interface{}{"provider": provider}})
+	writeJSON(w, f)
+}
+
+// requireSudoFlow fetches the current session and the settings Flow named
+// by ?request=, then enforces RequirePrivilegedSession against it - the
+// same gate StrategyTraits applies to protected traits applies here too,
+// since linking/unlinking are both sudo-gated operations.
+func (s *StrategyOIDC) requireSudoFlow(r *http.Request) (*session.Session, *Flow, error) {
+	sess, err := s.d.SessionManager().FetchFromRequest(r)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	id, err := uuid.FromString(r.URL.Query().Get("request"))
+	if err != nil {
+		return sess, nil, errors.WithStack(err)
+	}
+
+	f, err := s.d.SettingsFlowPersister().GetSettingsFlow(r.Context(), id)
+	if err != nil {
+		return sess, nil, errors.WithStack(err)
+	}
+
+	if err := RequirePrivilegedSession(s.d, r, sess); err != nil {
+		return sess, f, err
+	}
+
+	return sess, f, nil
+}
+
+// handleSudoFailure distinguishes "couldn't even identify the session/flow"
+// and "IsPrivileged itself failed, e.g. a misconfigured CA bundle" (no Flow
+// to redirect through, or an error that isn't ErrPrivilegedSessionRequired -
+// fail the request) from "session/flow were fine but aren't privileged
+// enough" (Flow is available - bounce through the login UI exactly like the
+// profile strategy's protected traits do).
+func (s *StrategyOIDC) handleSudoFailure(w http.ResponseWriter, r *http.Request, f *Flow, err error) {
+	if f == nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != ErrPrivilegedSessionRequired {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	RedirectToLogin(w, r, f)
+}
+
+func (s *StrategyOIDC) provider(id string) (OIDCProviderConfig, bool) {
+	for _, p := range s.d.OIDCProviders() {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return OIDCProviderConfig{}, false
+}
+
+func (s *StrategyOIDC) oauth2Config(p OIDCProviderConfig) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Scopes:       p.Scope,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthURL,
+			TokenURL: p.TokenURL,
+		},
+		RedirectURL: viper.GetString(configuration.ViperKeyURLsSelfPublic) + PublicPath + "/methods/oidc/callback/" + p.ID,
+	}
+}
+
+// LinkCredentials appends subject, scoped to provider, to i's oidc
+// credentials. It is a no-op if the pair is already linked, and refuses if
+// another identity has already claimed that provider/subject pair.
+func (s *StrategyOIDC) LinkCredentials(ctx context.Context, i *identity.Identity, provider, subject string) error {
+	identifier := provider + ":" + subject
+
+	existing, err := s.d.IdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypeOIDC, identifier)
+	if err == nil && existing != nil && existing.ID != i.ID {
+		return errors.Errorf("provider %q subject is already linked to a different identity", provider)
+	}
+
+	creds, ok := i.Credentials[identity.CredentialsTypeOIDC]
+	if !ok {
+		creds = identity.Credentials{Type: identity.CredentialsTypeOIDC}
+	}
+
+	for _, id := range creds.Identifiers {
+		if id == identifier {
+			return nil
+		}
+	}
+
+	creds.Identifiers = append(creds.Identifiers, identifier)
+	if i.Credentials == nil {
+		i.Credentials = map[identity.CredentialsType]identity.Credentials{}
+	}
+	i.Credentials[identity.CredentialsTypeOIDC] = creds
+
+	return s.d.IdentityPool().UpdateIdentity(ctx, i)
+}
+
+// UnlinkCredentials removes provider's identifier from i's oidc
+// credentials, refusing (ErrLastCredentialsLeftEmpty) if that would leave
+// the identity without any credential at all.
+func (s *StrategyOIDC) UnlinkCredentials(i *identity.Identity, provider string) error {
+	creds, ok := i.Credentials[identity.CredentialsTypeOIDC]
+	if !ok {
+		return errors.Errorf("identity has no oidc credentials to unlink %q from", provider)
+	}
+
+	remaining := creds.Identifiers[:0:0]
+	for _, id := range creds.Identifiers {
+		if p, _, ok := splitOIDCIdentifier(id); ok && p == provider {
+			continue
+		}
+		remaining = append(remaining, id)
+	}
+
+	wouldBeEmptyOIDC := len(remaining) == 0
+	onlyCredentialType := len(i.Credentials) == 1
+
+	if wouldBeEmptyOIDC && onlyCredentialType {
+		return ErrLastCredentialsLeftEmpty
+	}
+
+	if wouldBeEmptyOIDC {
+		delete(i.Credentials, identity.CredentialsTypeOIDC)
+	} else {
+		creds.Identifiers = remaining
+		i.Credentials[identity.CredentialsTypeOIDC] = creds
+	}
+
+	return nil
+}
+
+// LinkedProviders returns a provider -> subject map of the oidc providers i
+// currently has linked, so the settings payload can render "Connected as
+// john@gmail.com - Unlink" for each one.
+func LinkedProviders(i *identity.Identity) map[string]string {
+	linked := map[string]string{}
+
+	creds, ok := i.Credentials[identity.CredentialsTypeOIDC]
+	if !ok {
+		return linked
+	}
+
+	for _, id := range creds.Identifiers {
+		if provider, subject, ok := splitOIDCIdentifier(id); ok {
+			linked[provider] = subject
+		}
+	}
+
+	return linked
+}
+
+// verifierFor returns the cached *oidc.IDTokenVerifier for provider,
+// resolving its discovery document (and with it, the JWKS handleCallback's
+// id_token signatures are checked against) on first use. Reusing registered
+// Providers protects against the JWKS being re-fetched on every callback,
+// the same way the login/registration oidc strategy's client keeps its own
+// verifier around for the lifetime of the process.
+func (s *StrategyOIDC) verifierFor(ctx context.Context, p OIDCProviderConfig) (*oidc.IDTokenVerifier, error) {
+	s.verifiersMutex.Lock()
+	defer s.verifiersMutex.Unlock()
+
+	if v, ok := s.verifiers[p.ID]; ok {
+		return v, nil
+	}
+
+	oidcProvider, err := oidc.NewProvider(ctx, p.IssuerURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	v := oidcProvider.Verifier(&oidc.Config{ClientID: p.ClientID})
+	s.verifiers[p.ID] = v
+	return v, nil
+}
+
+// verifiedSubject verifies rawIDToken's signature, issuer and audience
+// against provider's discovery document before trusting its "sub" claim -
+// an unverified id_token would let a misbehaving provider, or one meant for
+// a different configured client, assert an arbitrary subject that
+// LinkCredentials would then happily attach to the current session's
+// identity.
+func (s *StrategyOIDC) verifiedSubject(ctx context.Context, provider OIDCProviderConfig, rawIDToken string) (string, error) {
+	if rawIDToken == "" {
+		return "", errors.New("token response carried no id_token")
+	}
+
+	verifier, err := s.verifierFor(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if claims.Subject == "" {
+		return "", errors.New("id_token has no sub claim")
+	}
+
+	return claims.Subject, nil
+}
+
+func splitOIDCIdentifier(id string) (provider, subject string, ok bool) {
+	for i := 0; i < len(id); i++ {
+		if id[i] == ':' {
+			return id[:i], id[i+1:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,165 @@
+package settings_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/x"
+)
+
+func TestStrategyOIDCLinking(t *testing.T) {
+	_, reg := internal.NewRegistryDefault(t)
+	s := settings.NewStrategyOIDC(reg)
+	ctx := context.Background()
+
+	newIdentity := func(t *testing.T, creds map[identity.CredentialsType]identity.Credentials) *identity.Identity {
+		i := &identity.Identity{ID: x.NewUUID(), Credentials: creds}
+		require.NoError(t, reg.IdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+
+	t.Run("case=linking a provider adds it to the identifiers", func(t *testing.T) {
+		i := newIdentity(t, map[identity.CredentialsType]identity.Credentials{
+			"password": {Type: "password", Identifiers: []string{"john@doe.com"}},
+		})
+
+		require.NoError(t, s.LinkCredentials(ctx, i, "google", "john@gmail.com"))
+
+		linked := settings.LinkedProviders(i)
+		assert.Equal(t, "john@gmail.com", linked["google"])
+	})
+
+	t.Run("case=linking the same provider/subject twice is a no-op", func(t *testing.T) {
+		i := newIdentity(t, nil)
+		require.NoError(t, s.LinkCredentials(ctx, i, "google", "john@gmail.com"))
+		require.NoError(t, s.LinkCredentials(ctx, i, "google", "john@gmail.com"))
+
+		creds := i.Credentials[identity.CredentialsTypeOIDC]
+		assert.Len(t, creds.Identifiers, 1)
+	})
+
+	t.Run("case=two identities cannot link the same provider subject", func(t *testing.T) {
+		first := newIdentity(t, nil)
+		second := newIdentity(t, map[identity.CredentialsType]identity.Credentials{
+			"password": {Type: "password", Identifiers: []string{"jane@doe.com"}},
+		})
+
+		require.NoError(t, s.LinkCredentials(ctx, first, "google", "shared-subject@gmail.com"))
+
+		err := s.LinkCredentials(ctx, second, "google", "shared-subject@gmail.com")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already linked to a different identity")
+
+		linked := settings.LinkedProviders(second)
+		assert.NotContains(t, linked, "google", "the collision must not have mutated the second identity")
+	})
+
+	t.Run("case=unlinking the only credential is rejected", func(t *testing.T) {
+		i := newIdentity(t, map[identity.CredentialsType]identity.Credentials{
+			identity.CredentialsTypeOIDC: {Type: identity.CredentialsTypeOIDC, Identifiers: []string{"google:john@gmail.com"}},
+		})
+
+		err := s.UnlinkCredentials(i, "google")
+		require.ErrorIs(t, err, settings.ErrLastCredentialsLeftEmpty)
+	})
+
+	t.Run("case=unlinking one of several providers keeps the others", func(t *testing.T) {
+		i := newIdentity(t, map[identity.CredentialsType]identity.Credentials{
+			"password":                   {Type: "password", Identifiers: []string{"john@doe.com"}},
+			identity.CredentialsTypeOIDC: {Type: identity.CredentialsTypeOIDC, Identifiers: []string{"google:john@gmail.com", "github:johndoe"}},
+		})
+
+		require.NoError(t, s.UnlinkCredentials(i, "google"))
+
+		linked := settings.LinkedProviders(i)
+		assert.NotContains(t, linked, "google")
+		assert.Equal(t, "johndoe", linked["github"])
+	})
+}
+
+// TestStrategyOIDCLinkRedirectsThroughSudo mirrors the protected-trait sudo
+// test: calling the link endpoint without a recent login must redirect
+// through the login UI rather than starting the OAuth dance, and succeeds in
+// starting it once the session is privileged.
+func TestStrategyOIDCLinkRedirectsThroughSudo(t *testing.T) {
+	_, reg := internal.NewRegistryDefault(t)
+	s := settings.NewStrategyOIDC(reg)
+	ctx := context.Background()
+
+	i := &identity.Identity{ID: x.NewUUID(), Credentials: map[identity.CredentialsType]identity.Credentials{
+		"password": {Type: "password", Identifiers: []string{"john@doe.com"}},
+	}}
+	require.NoError(t, reg.IdentityPool().CreateIdentity(ctx, i))
+
+	f := &settings.Flow{ID: x.NewUUID(), IdentityID: i.ID}
+	require.NoError(t, reg.SettingsFlowPersister().CreateSettingsFlow(ctx, &http.Request{}, f))
+
+	authTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("provider consent screen"))
+	}))
+	defer authTS.Close()
+	viper.Set(configuration.ViperKeySelfServiceOIDCProviders, []map[string]interface{}{{
+		"id":        "google",
+		"client_id": "google-client-id",
+		"auth_url":  authTS.URL + "/auth",
+		"token_url": authTS.URL + "/token",
+		"scope":     []string{"openid"},
+	}})
+	t.Cleanup(func() {
+		viper.Set(configuration.ViperKeySelfServiceOIDCProviders, nil)
+	})
+
+	router := httprouter.New()
+	s.RegisterPublicRoutes(router)
+	router.GET("/sessions/set/:index", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		http.SetCookie(w, &http.Cookie{Name: internal.SessionCookieName, Value: i.ID.String(), Path: "/"})
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	loginTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("called login page"))
+	}))
+	defer loginTS.Close()
+	viper.Set(configuration.ViperKeyURLsLogin, loginTS.URL+"/login")
+	viper.Set(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter, "1ns")
+
+	client := testhelpers.NewSessionClient(t, ts.URL+"/sessions/set/0")
+
+	t.Run("case=without a recent login the link endpoint redirects to the login UI", func(t *testing.T) {
+		res, err := client.Get(ts.URL + settings.PublicPath + "/methods/oidc/link/google?request=" + f.ID.String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, loginTS.URL+"/login", res.Request.URL.Scheme+"://"+res.Request.URL.Host+res.Request.URL.Path)
+	})
+
+	t.Run("case=with a recent login the link endpoint starts the OAuth dance", func(t *testing.T) {
+		viper.Set(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter, "5m")
+		t.Cleanup(func() {
+			viper.Set(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter, "1ns")
+		})
+
+		res, err := client.Get(ts.URL + settings.PublicPath + "/methods/oidc/link/google?request=" + f.ID.String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, authTS.URL+"/auth", res.Request.URL.Scheme+"://"+res.Request.URL.Host+res.Request.URL.Path, "should have actually redirected to the provider's authorization endpoint")
+		assert.Equal(t, "google-client-id", res.Request.URL.Query().Get("client_id"))
+		assert.Equal(t, "code", res.Request.URL.Query().Get("response_type"))
+		assert.NotEmpty(t, res.Request.URL.Query().Get("state"))
+	})
+}
@@ -0,0 +1,351 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/jsonschema/v3"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/form"
+)
+
+// StrategyTraitsID is the method name used throughout settings flows for the
+// JSON-schema-driven traits form (methods.profile.config.*).
+const StrategyTraitsID = "profile"
+
+// ErrProfileDataInvalid is returned by Settle when the submitted traits
+// fail validation against the identity's JSON schema. The individual
+// field-level messages are not carried on the error itself - Settle renders
+// them directly into f's "profile" form fields before returning, the same
+// place a successful Settle re-renders the (now-updated) values.
+var ErrProfileDataInvalid = errors.New("submitted profile data is invalid")
+
+// protectedTraits lists the trait paths that have always required a
+// privileged session, independent of any schema annotation. The list is
+// checked for every schema, in addition to the `ory.sh/kratos.scopes` rules
+// (see scope.go), so schemas that haven't opted into that vocabulary keep
+// the existing behaviour.
+var protectedTraits = map[string]bool{
+	"traits.email": true,
+}
+
+type profileDependencies interface {
+	configuration.Provider
+	identity.PoolProvider
+	identity.SchemaProvider
+	verification.ManagerProvider
+	UpdateCounterProvider
+}
+
+// StrategyTraits renders and validates the identity.Traits portion of a
+// settings Flow against the identity's JSON schema.
+type StrategyTraits struct {
+	d profileDependencies
+}
+
+func NewStrategyTraits(d profileDependencies) *StrategyTraits {
+	return &StrategyTraits{d: d}
+}
+
+func (s *StrategyTraits) ID() string {
+	return StrategyTraitsID
+}
+
+// RegisterPublicRoutes is a no-op: submissions are dispatched through
+// Handler.submitProfile at PublicSettingsProfilePath rather than a route
+// owned by the strategy itself.
+func (s *StrategyTraits) RegisterPublicRoutes(router *httprouter.Router) {}
+
+// PopulateSettingsMethod renders one form field per top-level trait present
+// on i, mirroring today's flattened "traits.<name>" naming.
+func (s *StrategyTraits) PopulateSettingsMethod(r *http.Request, i *identity.Identity, f *Flow) error {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	f.Methods[StrategyTraitsID] = &FlowMethod{Config: &form.HTMLForm{
+		Action: scheme + "://" + r.Host + PublicSettingsProfilePath + "?request=" + f.ID.String(),
+		Method: "POST",
+		Fields: renderTraitFields(string(i.Traits), nil),
+	}}
+
+	return nil
+}
+
+func fieldType(v gjson.Result) string {
+	switch v.Type {
+	case gjson.Number:
+		return "number"
+	case gjson.True, gjson.False:
+		return "checkbox"
+	default:
+		return "text"
+	}
+}
+
+// renderTraitFields builds the "profile" form's field list from traits,
+// attaching fieldErrs[name] (if any) to the matching field. It is used both
+// to populate a freshly created Flow and, by Settle, to re-render the form
+// with whatever was just submitted - valid or not.
+func renderTraitFields(traits string, fieldErrs map[string]string) form.Fields {
+	fields := form.Fields{}
+
+	gjson.Parse(traits).ForEach(func(key, value gjson.Result) bool {
+		name := "traits." + key.String()
+		field := form.Field{Name: name, Type: fieldType(value), Value: value.Value()}
+		if message, ok := fieldErrs[name]; ok {
+			field.Errors = []form.FieldError{{Message: message}}
+		}
+		fields = append(fields, field)
+		return true
+	})
+
+	return fields
+}
+
+// setTraitFields refreshes f's "profile" form fields in place, preserving
+// whatever Action/Method PopulateSettingsMethod originally set and the
+// form's CSRF token field.
+func setTraitFields(f *Flow, traits string, fieldErrs map[string]string) {
+	method, ok := f.Methods[StrategyTraitsID]
+	if !ok || method.Config == nil {
+		return
+	}
+
+	fields := renderTraitFields(traits, fieldErrs)
+	for _, field := range method.Config.Fields {
+		if field.Name == form.CSRFTokenName {
+			fields = append(form.Fields{field}, fields...)
+			break
+		}
+	}
+	method.Config.Fields = fields
+}
+
+// submittedTraits collects r's "traits.*" form fields into the
+// field -> value map TouchedScopes expects.
+func submittedTraits(r *http.Request) map[string]string {
+	submitted := map[string]string{}
+	for field, values := range r.PostForm {
+		if strings.HasPrefix(field, "traits.") {
+			submitted[field] = values[0]
+		}
+	}
+	return submitted
+}
+
+// mergeSubmittedTraits folds postForm's "traits.*" fields into traits,
+// preserving each field's existing JSON type where the submitted string
+// parses as one (a numeric trait stays a number, a boolean trait stays a
+// boolean) so that a successful update doesn't silently turn typed traits
+// into strings, and so that an unparsable submission (e.g. "numby=abc" for a
+// number trait) is left as the raw string for validateTraits to reject with
+// a type-mismatch error instead of a parse error.
+func mergeSubmittedTraits(traits string, postForm map[string][]string) string {
+	for field, values := range postForm {
+		if !strings.HasPrefix(field, "traits.") {
+			continue
+		}
+		path := strings.TrimPrefix(field, "traits.")
+
+		var value interface{} = values[0]
+		switch gjson.Get(traits, path).Type {
+		case gjson.Number:
+			if parsed, err := strconv.ParseFloat(values[0], 64); err == nil {
+				value = parsed
+			}
+		case gjson.True, gjson.False:
+			if parsed, err := strconv.ParseBool(values[0]); err == nil {
+				value = parsed
+			}
+		}
+
+		if updated, err := sjson.Set(traits, path, value); err == nil {
+			traits = updated
+		}
+	}
+	return traits
+}
+
+// validateTraits validates traits against the "traits" sub-schema of schema
+// (the same "properties.traits" document scope.go's schemaScopes reads its
+// `ory.sh/kratos.scopes` annotations from), returning one message per
+// invalid "traits.<path>" field.
+func validateTraits(schema json.RawMessage, traits string) (map[string]string, error) {
+	sub := gjson.GetBytes(schema, "properties.traits").Raw
+	if sub == "" {
+		return nil, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("traits.json", strings.NewReader(sub)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sch, err := compiler.Compile("traits.json")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(traits), &v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	fieldErrs := map[string]string{}
+	if verr := sch.Validate(v); verr != nil {
+		collectFieldErrors(verr, fieldErrs)
+	}
+
+	return fieldErrs, nil
+}
+
+// collectFieldErrors flattens a (possibly nested) *jsonschema.ValidationError
+// tree into one message per "traits.<path>" field, keeping only leaf causes
+// - the ones that actually failed a keyword - rather than the wrapping
+// "doesn't validate against ..." errors schema composition produces.
+func collectFieldErrors(err error, out map[string]string) {
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return
+	}
+
+	if len(verr.Causes) == 0 {
+		path := strings.ReplaceAll(strings.TrimPrefix(verr.InstanceLocation, "/"), "/", ".")
+		if path != "" {
+			out["traits."+path] = verr.Message
+		}
+		return
+	}
+
+	for _, cause := range verr.Causes {
+		collectFieldErrors(cause, out)
+	}
+}
+
+// scopeRules reads the selfservice.settings.scopes config map.
+func scopeRules() map[Scope]ScopeRule {
+	var rules map[Scope]ScopeRule
+	_ = viper.UnmarshalKey(configuration.ViperKeySelfServiceSettingsScopes, &rules)
+	return rules
+}
+
+// strictestTouchedRule diffs r's submission against i's stored traits using
+// i's own identity schema's `ory.sh/kratos.scopes` annotations, then folds
+// every touched scope down to the strictest configured ScopeRule.
+func (s *StrategyTraits) strictestTouchedRule(r *http.Request, i *identity.Identity) (ScopeRule, []Scope) {
+	schema, err := s.d.IdentityTraitsSchema(r.Context(), i.TraitsSchemaID)
+	if err != nil {
+		return ScopeRule{}, nil
+	}
+
+	touched := TouchedScopes(schema, i.Traits, submittedTraits(r))
+	return StrictestRule(scopeRules(), touched), touched
+}
+
+// TouchesSudoScope reports whether the current submission needs a privileged
+// session: either because it changes one of the hard-coded protectedTraits
+// (whatever scopes the schema declares), or because the
+// strictest scope it touches has require_sudo set (see scope.go).
+func (s *StrategyTraits) TouchesSudoScope(r *http.Request, i *identity.Identity) bool {
+	if err := r.ParseForm(); err != nil {
+		return false
+	}
+
+	for field := range protectedTraits {
+		submitted, ok := r.PostForm[field]
+		if !ok {
+			continue
+		}
+		path := strings.TrimPrefix(field, "traits.")
+		if gjson.GetBytes(i.Traits, path).String() != submitted[0] {
+			return true
+		}
+	}
+
+	rule, _ := s.strictestTouchedRule(r, i)
+	return rule.RequireSudo
+}
+
+// Settle validates the submitted form values against i's identity schema
+// and, if valid, returns an updated copy of i with the new traits merged in.
+// Invalid submissions return ErrProfileDataInvalid, having already rendered
+// the field-level errors into f's "profile" form. Scopes with a
+// MaxUpdatesPerDay cap are rejected once that cap is hit for the day; scopes
+// with RequireVerification queue a challenge via the verification subsystem
+// and mark f.PendingVerification instead of persisting the change outright -
+// in that case the touched scopes' daily quota is left untouched too, since
+// the change itself never took effect, and is only spent once a submission
+// actually persists.
+func (s *StrategyTraits) Settle(r *http.Request, i *identity.Identity, f *Flow) (*identity.Identity, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rule, touched := s.strictestTouchedRule(r, i)
+
+	for _, scope := range touched {
+		if rule.MaxUpdatesPerDay == 0 {
+			continue
+		}
+		count, err := s.d.SettingsUpdateCounter().CountUpdatesToday(r.Context(), i.ID, scope)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if count >= rule.MaxUpdatesPerDay {
+			return nil, ErrMaxUpdatesPerDayExceeded
+		}
+	}
+
+	traits := mergeSubmittedTraits(string(i.Traits), r.PostForm)
+
+	schema, err := s.d.IdentityTraitsSchema(r.Context(), i.TraitsSchemaID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	fieldErrs, err := validateTraits(schema, traits)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(fieldErrs) > 0 {
+		setTraitFields(f, traits, fieldErrs)
+		return nil, ErrProfileDataInvalid
+	}
+
+	updated := *i
+	updated.Traits = identity.Traits(traits)
+
+	if rule.RequireVerification != "" && rule.RequireVerification != "none" {
+		if err := s.d.VerificationManager().SendChallenge(r.Context(), &updated, rule.RequireVerification); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f.PendingVerification = true
+	} else {
+		for _, scope := range touched {
+			if rule.MaxUpdatesPerDay == 0 {
+				continue
+			}
+			if err := s.d.SettingsUpdateCounter().RecordUpdate(r.Context(), i.ID, scope); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	setTraitFields(f, traits, nil)
+
+	return &updated, nil
+}
@@ -0,0 +1,157 @@
+package settings_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/x"
+)
+
+// fakeProfileDependencies is a minimal, in-memory stand-in for the registry
+// so this test can exercise StrategyTraits.TouchesSudoScope/Settle directly
+// against the scoped schema, instead of only StrictestRule/TouchedScopes in
+// isolation (see scope_test.go).
+type fakeProfileDependencies struct {
+	schema  json.RawMessage
+	counts  map[string]int
+	sentVia []string
+}
+
+func (f *fakeProfileDependencies) Configuration() configuration.Configuration { return nil }
+func (f *fakeProfileDependencies) IdentityPool() identity.Pool               { return nil }
+func (f *fakeProfileDependencies) IdentityTraitsSchema(context.Context, string) (json.RawMessage, error) {
+	return f.schema, nil
+}
+
+func (f *fakeProfileDependencies) VerificationManager() verification.Manager {
+	return fakeVerificationManager{f}
+}
+
+type fakeVerificationManager struct{ f *fakeProfileDependencies }
+
+func (v fakeVerificationManager) SendChallenge(_ context.Context, _ *identity.Identity, via string) error {
+	v.f.sentVia = append(v.f.sentVia, via)
+	return nil
+}
+
+func (f *fakeProfileDependencies) SettingsUpdateCounter() settings.UpdateCounter {
+	return fakeUpdateCounter{f}
+}
+
+type fakeUpdateCounter struct{ f *fakeProfileDependencies }
+
+func (c fakeUpdateCounter) CountUpdatesToday(_ context.Context, identityID uuid.UUID, scope settings.Scope) (int, error) {
+	return c.f.counts[identityID.String()+string(scope)], nil
+}
+
+func (c fakeUpdateCounter) RecordUpdate(_ context.Context, identityID uuid.UUID, scope settings.Scope) error {
+	if c.f.counts == nil {
+		c.f.counts = map[string]int{}
+	}
+	c.f.counts[identityID.String()+string(scope)]++
+	return nil
+}
+
+func postForm(values url.Values) *http.Request {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// TestStrategyTraitsScopeWiring exercises the real StrategyTraits against a
+// schema that declares `ory.sh/kratos.scopes`: a profile:basic field settles
+// without sudo or verification, while a profile:sensitive field both demands
+// sudo and queues a verification challenge - the acceptance example from
+// chunk0-4, run against the strategy itself rather than StrictestRule alone.
+func TestStrategyTraitsScopeWiring(t *testing.T) {
+	deps := &fakeProfileDependencies{schema: scopedSchema}
+	s := settings.NewStrategyTraits(deps)
+
+	viper.Set(configuration.ViperKeySelfServiceSettingsScopes, map[settings.Scope]settings.ScopeRule{
+		"profile:basic":     {RequireSudo: false},
+		"profile:sensitive": {RequireSudo: true, RequireVerification: "email"},
+	})
+	t.Cleanup(func() {
+		viper.Set(configuration.ViperKeySelfServiceSettingsScopes, nil)
+	})
+
+	i := &identity.Identity{
+		ID:     x.NewUUID(),
+		Traits: identity.Traits(`{"email":"john@doe.com","should_big_number":2048}`),
+	}
+	f := &settings.Flow{ID: x.NewUUID(), IdentityID: i.ID}
+
+	t.Run("case=updating the basic-scope field needs no sudo and settles immediately", func(t *testing.T) {
+		values := url.Values{"traits.should_big_number": {"4096"}}
+
+		r := postForm(values)
+		require.NoError(t, r.ParseForm())
+		assert.False(t, s.TouchesSudoScope(r, i))
+
+		updated, err := s.Settle(r, i, f)
+		require.NoError(t, err)
+		assert.False(t, f.PendingVerification)
+		assert.Equal(t, 4096.0, gjson.GetBytes(updated.Traits, "should_big_number").Value())
+	})
+
+	t.Run("case=updating the sensitive-scope field requires sudo and queues verification", func(t *testing.T) {
+		values := url.Values{"traits.email": {"not-john-doe@mail.com"}}
+
+		r := postForm(values)
+		require.NoError(t, r.ParseForm())
+		assert.True(t, s.TouchesSudoScope(r, i))
+
+		f := &settings.Flow{ID: x.NewUUID(), IdentityID: i.ID}
+		_, err := s.Settle(r, i, f)
+		require.NoError(t, err)
+		assert.True(t, f.PendingVerification)
+		assert.Equal(t, []string{"email"}, deps.sentVia)
+	})
+}
+
+// TestStrategyTraitsMaxUpdatesPerDay exercises ScopeRule.MaxUpdatesPerDay:
+// once a scope's daily cap is reached, Settle must refuse further updates to
+// it rather than silently allowing them.
+func TestStrategyTraitsMaxUpdatesPerDay(t *testing.T) {
+	deps := &fakeProfileDependencies{schema: scopedSchema}
+	s := settings.NewStrategyTraits(deps)
+
+	viper.Set(configuration.ViperKeySelfServiceSettingsScopes, map[settings.Scope]settings.ScopeRule{
+		"profile:basic": {MaxUpdatesPerDay: 1},
+	})
+	t.Cleanup(func() {
+		viper.Set(configuration.ViperKeySelfServiceSettingsScopes, nil)
+	})
+
+	i := &identity.Identity{
+		ID:     x.NewUUID(),
+		Traits: identity.Traits(`{"should_big_number":2048}`),
+	}
+	f := &settings.Flow{ID: x.NewUUID(), IdentityID: i.ID}
+
+	r := postForm(url.Values{"traits.should_big_number": {"4096"}})
+	require.NoError(t, r.ParseForm())
+	updated, err := s.Settle(r, i, f)
+	require.NoError(t, err)
+
+	r2 := postForm(url.Values{"traits.should_big_number": {"8192"}})
+	require.NoError(t, r2.ParseForm())
+	_, err = s.Settle(r2, updated, f)
+	require.ErrorIs(t, err, settings.ErrMaxUpdatesPerDayExceeded)
+}
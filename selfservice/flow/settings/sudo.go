@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/viper"
+
+	"github.com/ory/kratos/driver/configuration"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+type sudoDependencies interface {
+	identity.PoolProvider
+}
+
+// ErrPrivilegedSessionRequired is returned by RequirePrivilegedSession when
+// sess genuinely isn't privileged - neither a recent login nor a matching
+// client certificate. Callers must check for this specific error before
+// falling back to the login redirect: any other error (e.g. a misconfigured
+// CA bundle) means IsPrivileged could not be evaluated at all and should
+// surface as a failure instead of being indistinguishable from "not
+// privileged".
+var ErrPrivilegedSessionRequired = errors.New("privileged session required")
+
+// RequirePrivilegedSession returns nil if sess is allowed to perform a
+// sudo-gated action without being redirected through the login UI - either
+// because it authenticated recently enough
+// (ViperKeySelfServicePrivilegedAuthenticationAfter) or because r carries a
+// client certificate that CertificateAuthenticator maps to sess's identity.
+//
+// This is shared by the "profile" strategy's protected-trait check and the
+// "oidc" strategy's link/unlink handlers, since both guard sudo-gated
+// changes to the same identity.
+func RequirePrivilegedSession(d sudoDependencies, r *http.Request, sess *session.Session) error {
+	maxAge := viper.GetDuration(configuration.ViperKeySelfServicePrivilegedAuthenticationAfter)
+	if time.Since(sess.AuthenticatedAt) < maxAge {
+		return nil
+	}
+
+	privileged, err := NewCertificateAuthenticator(d).IsPrivileged(r, sess.Identity)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if privileged {
+		return nil
+	}
+
+	return ErrPrivilegedSessionRequired
+}
+
+// RedirectToLogin sends the browser to the configured login UI for f,
+// carrying return_to so it bounces back here once re-authenticated.
+func RedirectToLogin(w http.ResponseWriter, r *http.Request, f *Flow) {
+	loginURL := viper.GetString(configuration.ViperKeyURLsLogin)
+	http.Redirect(w, r, loginURL+"?request="+f.ID.String()+"&return_to="+r.URL.String(), http.StatusFound)
+}
@@ -0,0 +1,26 @@
+// Package verification is the narrow slice of the identity verification
+// subsystem that settings scope enforcement needs: a way to start a
+// challenge for a trait value before it is trusted. The rest of the
+// verification flow (issuing and redeeming the token, its own Flow/Handler)
+// lives alongside it and is out of scope here.
+package verification
+
+import (
+	"context"
+
+	"github.com/ory/kratos/identity"
+)
+
+// Manager starts a verification challenge for one of i's trait values - e.g.
+// emailing a confirmation link to a newly submitted address - identified by
+// via (the ScopeRule.RequireVerification strategy, such as "email").
+type Manager interface {
+	SendChallenge(ctx context.Context, i *identity.Identity, via string) error
+}
+
+// ManagerProvider is embedded by any dependencies interface that needs to
+// start verification challenges, following the same pattern as
+// identity.PoolProvider and session.ManagerProvider.
+type ManagerProvider interface {
+	VerificationManager() Manager
+}
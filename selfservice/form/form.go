@@ -0,0 +1,43 @@
+// Package form models the generic HTML form every self-service strategy
+// (profile, oidc, ...) renders into a settings.FlowMethod: a POST
+// action/method and a flat list of fields, each carrying whatever value and
+// validation errors currently apply to it.
+package form
+
+// CSRFTokenName is the hidden field name every rendered form carries its
+// CSRF token under.
+const CSRFTokenName = "csrf_token"
+
+// FieldError is a single validation error attached to a Field, e.g. one
+// produced by validating a submitted trait against its JSON schema.
+type FieldError struct {
+	Message string `json:"message"`
+}
+
+// Field is a single input of an HTMLForm.
+//
+// Action and Method override the owning HTMLForm's own Action/Method for
+// this field alone, for strategies (e.g. oidc's per-provider link/unlink
+// buttons) whose fields don't all share one form-level target - a client
+// that only ever reads HTMLForm.Action/Method, ignoring a field's own, would
+// hit whatever the form-level target happens to be instead of what this
+// field actually wants submitted to.
+type Field struct {
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	Value    interface{}  `json:"value,omitempty"`
+	Required bool         `json:"required,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	Action   string       `json:"action,omitempty"`
+	Method   string       `json:"method,omitempty"`
+}
+
+// Fields is a rendered form's field list, in submission order.
+type Fields []Field
+
+// HTMLForm is what a Strategy renders into FlowMethod.Config.
+type HTMLForm struct {
+	Action string `json:"action"`
+	Method string `json:"method"`
+	Fields Fields `json:"fields"`
+}
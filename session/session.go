@@ -0,0 +1,27 @@
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+)
+
+// Session represents an authenticated browser session.
+type Session struct {
+	ID              uuid.UUID          `json:"id"`
+	Identity        *identity.Identity `json:"identity"`
+	AuthenticatedAt time.Time          `json:"authenticated_at"`
+	ExpiresAt       time.Time          `json:"expires_at"`
+}
+
+// Manager resolves the Session, if any, associated with an incoming request.
+type Manager interface {
+	FetchFromRequest(r *http.Request) (*Session, error)
+}
+
+type ManagerProvider interface {
+	SessionManager() Manager
+}
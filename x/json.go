@@ -0,0 +1,16 @@
+package x
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WriteJSON writes v to w as the standard JSON response every handler in
+// this tree returns: an indented-free document with the matching
+// Content-Type. r is accepted (rather than just w) so future content
+// negotiation (e.g. honouring Accept) has somewhere to live without
+// changing every call site's signature.
+func WriteJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
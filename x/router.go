@@ -0,0 +1,16 @@
+package x
+
+import "github.com/julienschmidt/httprouter"
+
+// NewRouterPublic and NewRouterAdmin construct the httprouter.Router
+// instances the public and admin APIs are served on. They are kept as two
+// separate constructors, rather than one shared NewRouter, because the admin
+// router is expected to grow its own middleware stack (e.g. restricting it
+// to a private network) that the public router must never inherit.
+func NewRouterPublic() *httprouter.Router {
+	return httprouter.New()
+}
+
+func NewRouterAdmin() *httprouter.Router {
+	return httprouter.New()
+}
@@ -0,0 +1,18 @@
+package x
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// MustEncodeJSON encodes v as JSON, failing t immediately if it cannot. It
+// exists so tests can compare a typed value (e.g. identity.Traits) against a
+// raw JSON document with assert.JSONEq without each test hand-rolling the
+// same json.Marshal/require.NoError pair.
+func MustEncodeJSON(t *testing.T, v interface{}) string {
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(out)
+}
@@ -0,0 +1,19 @@
+// Package x collects the small cross-cutting helpers used throughout this
+// tree that don't belong to any single domain package: UUID generation, the
+// shared JSON response helper, and the two httprouter.Router instances the
+// public and admin APIs are served on.
+package x
+
+import "github.com/gofrs/uuid"
+
+// NewUUID returns a new random UUID. Every call site treats ID generation as
+// infallible, so a failure here (the system's random source being
+// unavailable) is not recoverable and panics rather than threading an error
+// return through every constructor that mints one.
+func NewUUID() uuid.UUID {
+	id, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}